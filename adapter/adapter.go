@@ -1,6 +1,10 @@
 package adapter
 
 import (
+	"bytes"
+	"fmt"
+	"text/template"
+
 	"github.com/prebid/openrtb/v20/openrtb2"
 )
 
@@ -10,6 +14,9 @@ type HttpRequest struct {
 	Uri     string
 	Body    []byte
 	Headers map[string]string
+	// ContentEncoding, when set to "gzip", tells the handler to gzip Body
+	// before sending it and to set the Content-Encoding header itself.
+	ContentEncoding string
 }
 
 // HttpResponse represents a response from a bidder
@@ -28,6 +35,9 @@ type TypedBid struct {
 // BidderResponse wraps the server's response with the list of bids
 type BidderResponse struct {
 	Bids []*TypedBid
+	// Currency is the currency the bidder denominated Bids' prices in, so
+	// NormalizeBids can convert them into the request's requested currency.
+	Currency string
 }
 
 // Bidder interface for bidding
@@ -39,7 +49,35 @@ type Bidder interface {
 	MakeBids(request *openrtb2.BidRequest, response *HttpResponse) (*BidderResponse, []error)
 }
 
-// Builder builds a new instance of the bidder
+// Builder builds a new instance of the bidder from the raw bytes of
+// pbs.yaml. Each adapter parses out its own section.
 type Builder interface {
-	BuildBidder(params interface{}) (Bidder, error)
+	BuildBidder(config []byte) (Bidder, error)
+}
+
+// Registry holds the set of bidders that were successfully built from
+// pbs.yaml, keyed by bidder name (e.g. "smartadserver"). The key is the
+// same name used in imp.ext.prebid.bidder.<name> and as the "<name>_backend"
+// Fastly backend.
+type Registry map[string]Bidder
+
+// EndpointMacros holds the values an adapter's endpoint template may
+// reference. Not every adapter uses every field; unused fields resolve to
+// the empty string.
+type EndpointMacros struct {
+	Host        string
+	AccountID   string
+	ZoneID      string
+	PublisherID string
+	SourceID    string
+}
+
+// ResolveEndpoint executes an adapter's endpoint template with the given
+// macros and returns the resulting URI.
+func ResolveEndpoint(tmpl *template.Template, macros EndpointMacros) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, macros); err != nil {
+		return "", fmt.Errorf("error resolving endpoint template: %v", err)
+	}
+	return buf.String(), nil
 }
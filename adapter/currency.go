@@ -0,0 +1,70 @@
+package adapter
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// CurrencyConverter converts an amount denominated in one currency into
+// another. Implementations must be safe for concurrent use, since bidders
+// are normalized concurrently.
+type CurrencyConverter interface {
+	Convert(amount float64, from, to string) (float64, error)
+}
+
+// DefaultRates is the rate table StaticCurrencyConverter starts with when
+// the WASM binary boots. rates[from][to] is the multiplier that converts an
+// amount in `from` into `to`. It is deliberately small; adapters that need
+// more currencies should refresh it via LoadRates.
+var DefaultRates = map[string]map[string]float64{
+	"USD": {"USD": 1, "EUR": 0.92, "GBP": 0.79},
+	"EUR": {"EUR": 1, "USD": 1.09, "GBP": 0.86},
+	"GBP": {"GBP": 1, "USD": 1.27, "EUR": 1.16},
+}
+
+// StaticCurrencyConverter is a CurrencyConverter backed by a rate table
+// embedded in the WASM binary. The table can optionally be replaced at
+// runtime, e.g. after fetching a fresh one from a Fastly backend.
+type StaticCurrencyConverter struct {
+	mu    sync.RWMutex
+	rates map[string]map[string]float64
+}
+
+// NewStaticCurrencyConverter builds a StaticCurrencyConverter seeded with
+// the given rate table.
+func NewStaticCurrencyConverter(rates map[string]map[string]float64) *StaticCurrencyConverter {
+	return &StaticCurrencyConverter{rates: rates}
+}
+
+// Convert converts amount from one currency to another. Converting a
+// currency to itself, or when either side is unset, is always a no-op.
+func (c *StaticCurrencyConverter) Convert(amount float64, from, to string) (float64, error) {
+	if from == "" || to == "" || from == to {
+		return amount, nil
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	rate, ok := c.rates[from][to]
+	if !ok {
+		return 0, fmt.Errorf("no conversion rate from %s to %s", from, to)
+	}
+	return amount * rate, nil
+}
+
+// LoadRates replaces the converter's rate table wholesale, e.g. with a
+// fresher one fetched from a Fastly backend. The JSON shape is the same as
+// DefaultRates: {"USD": {"EUR": 0.92, ...}, ...}.
+func (c *StaticCurrencyConverter) LoadRates(data []byte) error {
+	var rates map[string]map[string]float64
+	if err := json.Unmarshal(data, &rates); err != nil {
+		return fmt.Errorf("error parsing currency rates: %v", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rates = rates
+	return nil
+}
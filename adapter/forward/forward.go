@@ -0,0 +1,126 @@
+// Package forward implements adapter.Bidder for SSPs that need nothing
+// more than their incoming OpenRTB request forwarded unmodified and a
+// standard OpenRTB response read back. Aso, Driftpixel, MediaGo, Adtarget,
+// OpenWeb, and Adagio all share this one implementation, parameterized by
+// name (Adagio additionally gzips its request bodies).
+package forward
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"gopkg.in/yaml.v2"
+
+	"prebid-fastly-compute/adapter"
+
+	"github.com/prebid/openrtb/v20/openrtb2"
+)
+
+// pbsConfig is the adapters section of pbs.yaml, read generically so one
+// Builder can serve any bidder name.
+type pbsConfig struct {
+	Adapters map[string]struct {
+		Enabled  bool   `yaml:"enabled"`
+		Endpoint string `yaml:"endpoint"`
+	} `yaml:"adapters"`
+}
+
+// Builder builds a new forwarding bidder instance for a single SSP. Name is
+// the key used in pbs.yaml's "adapters" section and in
+// imp.ext.prebid.bidder.<name>; DisplayName is used in error messages
+// (e.g. "MediaGo" for name "mediago"). Gzip marks SSPs that require
+// gzip-compressed request bodies, e.g. Adagio.
+type Builder struct {
+	Name        string
+	DisplayName string
+	Gzip        bool
+}
+
+// BuildBidder creates a new bidder from the PBS config.
+func (b *Builder) BuildBidder(config []byte) (adapter.Bidder, error) {
+	var cfg pbsConfig
+	if err := yaml.Unmarshal(config, &cfg); err != nil {
+		return nil, fmt.Errorf("error parsing PBS config: %v", err)
+	}
+
+	if !cfg.Adapters[b.Name].Enabled {
+		return nil, fmt.Errorf("%s adapter is not enabled in config", b.DisplayName)
+	}
+
+	return &Adapter{
+		displayName: b.DisplayName,
+		endpoint:    cfg.Adapters[b.Name].Endpoint,
+		gzip:        b.Gzip,
+	}, nil
+}
+
+// Adapter is a minimal implementation of adapter.Bidder. It forwards the
+// incoming OpenRTB request unmodified and reads back a standard OpenRTB
+// response; it does not yet support bidder-specific imp params or macros.
+type Adapter struct {
+	displayName string
+	endpoint    string
+	gzip        bool
+}
+
+// MakeRequests creates the HTTP request to fetch a bid.
+func (a *Adapter) MakeRequests(request *openrtb2.BidRequest) ([]*adapter.HttpRequest, []error) {
+	if len(request.Imp) == 0 {
+		return nil, []error{fmt.Errorf("no impressions in bid request")}
+	}
+
+	reqBody, err := json.Marshal(request)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	req := &adapter.HttpRequest{
+		Method: "POST",
+		Uri:    a.endpoint,
+		Body:   reqBody,
+		Headers: map[string]string{
+			"Content-Type": "application/json;charset=utf-8",
+			"Accept":       "application/json",
+		},
+	}
+	if a.gzip {
+		req.ContentEncoding = "gzip"
+	}
+
+	return []*adapter.HttpRequest{req}, nil
+}
+
+// MakeBids unpacks the server's response into Bids.
+func (a *Adapter) MakeBids(request *openrtb2.BidRequest, response *adapter.HttpResponse) (*adapter.BidderResponse, []error) {
+	if response.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, []error{fmt.Errorf("unexpected status code: %d", response.StatusCode)}
+	}
+
+	var bidResp openrtb2.BidResponse
+	if err := json.Unmarshal(response.Body, &bidResp); err != nil {
+		return nil, []error{err}
+	}
+
+	currency := bidResp.Cur
+	if currency == "" {
+		currency = "USD"
+	}
+
+	bidResponse := adapter.BidderResponse{
+		Bids:     make([]*adapter.TypedBid, 0),
+		Currency: currency,
+	}
+	for _, seatBid := range bidResp.SeatBid {
+		for i := range seatBid.Bid {
+			bidResponse.Bids = append(bidResponse.Bids, &adapter.TypedBid{
+				Bid: &seatBid.Bid[i],
+			})
+		}
+	}
+
+	return &bidResponse, nil
+}
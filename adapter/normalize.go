@@ -0,0 +1,107 @@
+package adapter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Bid media types, as used in TypedBid.BidType and bid.ext.prebid.type.
+const (
+	BidTypeBanner = "banner"
+	BidTypeVideo  = "video"
+	BidTypeNative = "native"
+)
+
+// bidExtProps is the subset of bid.ext this package inspects to detect a
+// bid's media type.
+type bidExtProps struct {
+	Prebid struct {
+		Type string `json:"type"`
+	} `json:"prebid"`
+}
+
+// DetectBidType returns tb.BidType if the adapter already set one,
+// otherwise it infers the type from bid.ext.prebid.type and, failing that,
+// from the shape of the creative markup itself (VAST XML means video, a
+// native JSON payload means native, anything else is treated as banner).
+func DetectBidType(tb *TypedBid) string {
+	if tb.BidType != "" {
+		return tb.BidType
+	}
+
+	if len(tb.Bid.Ext) > 0 {
+		var ext bidExtProps
+		if err := json.Unmarshal(tb.Bid.Ext, &ext); err == nil && ext.Prebid.Type != "" {
+			return ext.Prebid.Type
+		}
+	}
+
+	adm := strings.TrimSpace(tb.Bid.AdM)
+	switch {
+	case strings.Contains(adm, "<VAST"):
+		return BidTypeVideo
+	case strings.HasPrefix(adm, "{") && strings.Contains(adm, "\"native\""):
+		return BidTypeNative
+	default:
+		return BidTypeBanner
+	}
+}
+
+// NormalizeOptions configures NormalizeBids for a single bidder's response.
+type NormalizeOptions struct {
+	// AllowedMediaTypes restricts which bid types survive normalization. An
+	// empty slice means every media type is allowed.
+	AllowedMediaTypes []string
+
+	// SeatCurrency is the currency the bidder denominated its prices in.
+	SeatCurrency string
+
+	// TargetCurrency is the currency the final response should be
+	// denominated in (request.Cur[0]).
+	TargetCurrency string
+
+	// Converter performs the currency conversion. If nil, prices are left
+	// as-is regardless of SeatCurrency/TargetCurrency.
+	Converter CurrencyConverter
+}
+
+// NormalizeBids infers each bid's media type, drops any bid whose type
+// isn't in opts.AllowedMediaTypes, and converts surviving bids' prices into
+// opts.TargetCurrency. It returns the surviving bids plus one error per
+// dropped or unconvertible bid.
+func NormalizeBids(bids []*TypedBid, opts NormalizeOptions) ([]*TypedBid, []error) {
+	var errs []error
+	normalized := make([]*TypedBid, 0, len(bids))
+
+	for _, tb := range bids {
+		bidType := DetectBidType(tb)
+		if len(opts.AllowedMediaTypes) > 0 && !contains(opts.AllowedMediaTypes, bidType) {
+			errs = append(errs, fmt.Errorf("dropping bid %s: media type %q is not allowed", tb.Bid.ID, bidType))
+			continue
+		}
+		tb.BidType = bidType
+
+		if opts.Converter != nil && opts.SeatCurrency != "" && opts.TargetCurrency != "" {
+			converted, err := opts.Converter.Convert(tb.Bid.Price, opts.SeatCurrency, opts.TargetCurrency)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("dropping bid %s: %v", tb.Bid.ID, err))
+				continue
+			}
+			tb.Bid.Price = converted
+		}
+
+		normalized = append(normalized, tb)
+	}
+
+	return normalized, errs
+}
+
+func contains(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
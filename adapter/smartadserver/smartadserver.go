@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"text/template"
 
 	"gopkg.in/yaml.v2"
 
@@ -19,6 +21,7 @@ type PBSConfig struct {
 		SmartAdServer struct {
 			Enabled       bool   `yaml:"enabled"`
 			Endpoint      string `yaml:"endpoint"`
+			Host          string `yaml:"host"`
 			PlatformID    int    `yaml:"platform-id"`
 			DefaultConfig struct {
 				SiteID     int `yaml:"site-id"`
@@ -44,9 +47,15 @@ func (b *Builder) BuildBidder(config []byte) (adapter.Bidder, error) {
 		return nil, fmt.Errorf("SmartAdServer adapter is not enabled in config")
 	}
 
+	endpointTmpl, err := template.New("endpoint").Parse(pbsConfig.Adapters.SmartAdServer.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing SmartAdServer endpoint template: %v", err)
+	}
+
 	adapter := &SmartAdServerAdapter{
-		endpoint:   pbsConfig.Adapters.SmartAdServer.Endpoint,
-		platformID: pbsConfig.Adapters.SmartAdServer.PlatformID,
+		endpointTmpl: endpointTmpl,
+		host:         pbsConfig.Adapters.SmartAdServer.Host,
+		platformID:   pbsConfig.Adapters.SmartAdServer.PlatformID,
 	}
 	adapter.defaultConfig.SiteID = pbsConfig.Adapters.SmartAdServer.DefaultConfig.SiteID
 	adapter.defaultConfig.PageID = pbsConfig.Adapters.SmartAdServer.DefaultConfig.PageID
@@ -58,7 +67,8 @@ func (b *Builder) BuildBidder(config []byte) (adapter.Bidder, error) {
 
 // SmartAdServerAdapter implements Bidder interface for SmartAdServer
 type SmartAdServerAdapter struct {
-	endpoint      string
+	endpointTmpl  *template.Template
+	host          string
 	platformID    int
 	defaultConfig struct {
 		SiteID     int `yaml:"site-id"`
@@ -70,7 +80,6 @@ type SmartAdServerAdapter struct {
 
 // MakeRequests creates http requests to fetch bids
 func (a *SmartAdServerAdapter) MakeRequests(request *openrtb2.BidRequest) ([]*adapter.HttpRequest, []error) {
-	log.Printf("[SmartAdServer] Starting bid request to endpoint: %s", a.endpoint)
 	log.Printf("[SmartAdServer] Configured values:")
 	log.Printf("  SiteID: %d", a.defaultConfig.SiteID)
 	log.Printf("  PageID: %d", a.defaultConfig.PageID)
@@ -82,9 +91,28 @@ func (a *SmartAdServerAdapter) MakeRequests(request *openrtb2.BidRequest) ([]*ad
 		return nil, []error{fmt.Errorf("no impressions in bid request")}
 	}
 
+	macros := adapter.EndpointMacros{
+		Host:      a.host,
+		AccountID: strconv.Itoa(a.platformID),
+	}
+	if request.Site != nil && request.Site.Publisher != nil {
+		macros.PublisherID = request.Site.Publisher.ID
+	}
+
+	endpoint, err := adapter.ResolveEndpoint(a.endpointTmpl, macros)
+	if err != nil {
+		return nil, []error{err}
+	}
+	log.Printf("[SmartAdServer] Resolved endpoint: %s", endpoint)
+
 	// Create a copy of the first impression to modify
 	imp := request.Imp[0]
 
+	var domain string
+	if request.Site != nil {
+		domain = request.Site.Domain
+	}
+
 	// Add SmartAdServer specific extension
 	impExt := map[string]interface{}{
 		"prebid": map[string]interface{}{
@@ -95,7 +123,7 @@ func (a *SmartAdServerAdapter) MakeRequests(request *openrtb2.BidRequest) ([]*ad
 					"pageId":    a.defaultConfig.PageID,
 					"formatId":  a.defaultConfig.FormatID,
 					"target":    "testing=prebid",
-					"domain":    request.Site.Domain, // Add domain if available
+					"domain":    domain, // Add domain if available
 				}},
 		},
 	}
@@ -118,9 +146,14 @@ func (a *SmartAdServerAdapter) MakeRequests(request *openrtb2.BidRequest) ([]*ad
 		}
 	}
 
-	// Set bidfloor information
-	imp.BidFloor = 0.01
-	imp.BidFloorCur = "USD"
+	// The floors subsystem (see the floors package) sets BidFloor/
+	// BidFloorCur on every imp before MakeRequests runs. Fall back to a
+	// nominal floor if it's still unset, e.g. local testing without
+	// ext.prebid.floors configured on the request.
+	if imp.BidFloor <= 0 {
+		imp.BidFloor = 0.01
+		imp.BidFloorCur = "USD"
+	}
 
 	// Create enriched bid request
 	enrichedRequest := *request // Make a copy
@@ -144,7 +177,7 @@ func (a *SmartAdServerAdapter) MakeRequests(request *openrtb2.BidRequest) ([]*ad
 	}
 
 	log.Printf("[SmartAdServer] Full request details:")
-	log.Printf("  URL: %s", a.endpoint)
+	log.Printf("  URL: %s", endpoint)
 	log.Printf("  Method: POST")
 	log.Printf("  Headers:")
 	headers := map[string]string{
@@ -160,7 +193,7 @@ func (a *SmartAdServerAdapter) MakeRequests(request *openrtb2.BidRequest) ([]*ad
 	return []*adapter.HttpRequest{
 		{
 			Method:  "POST",
-			Uri:     a.endpoint,
+			Uri:     endpoint,
 			Body:    reqBody,
 			Headers: headers,
 		},
@@ -202,15 +235,22 @@ func (a *SmartAdServerAdapter) MakeBids(request *openrtb2.BidRequest, response *
 		return nil, []error{err}
 	}
 
+	currency := bidResp.Cur
+	if currency == "" {
+		currency = "USD"
+	}
+
 	bidResponse := adapter.BidderResponse{
-		Bids: make([]*adapter.TypedBid, 0),
+		Bids:     make([]*adapter.TypedBid, 0),
+		Currency: currency,
 	}
 
 	for _, seatBid := range bidResp.SeatBid {
 		for i := range seatBid.Bid {
+			// BidType is left empty here; adapter.NormalizeBids infers it
+			// from bid.ext.prebid.type or the creative markup.
 			bidResponse.Bids = append(bidResponse.Bids, &adapter.TypedBid{
-				Bid:     &seatBid.Bid[i],
-				BidType: "banner", // SmartAdServer typically returns banner ads
+				Bid: &seatBid.Bid[i],
 			})
 		}
 	}
@@ -0,0 +1,18 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrStoredRequestNotFound is returned by a StoredRequestFetcher when the
+// given id has no corresponding stored request or stored impression.
+var ErrStoredRequestNotFound = errors.New("stored request not found")
+
+// StoredRequestFetcher resolves stored request and stored impression JSON
+// fragments by id, as referenced from ext.prebid.storedrequest.id at the
+// request level and at the imp level.
+type StoredRequestFetcher interface {
+	FetchStoredRequest(ctx context.Context, id string) ([]byte, error)
+	FetchStoredImp(ctx context.Context, id string) ([]byte, error)
+}
@@ -0,0 +1,43 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+)
+
+// FSStoredRequestFetcher resolves stored requests and stored impressions
+// from an fs.FS (typically an embed.FS bundled into the binary), so stored
+// requests still resolve during local `viceroy` runs where no KV store is
+// available.
+type FSStoredRequestFetcher struct {
+	fsys fs.FS
+}
+
+// NewFSStoredRequestFetcher wraps fsys as a StoredRequestFetcher. Stored
+// requests are read from "request/<id>.json", stored impressions from
+// "imp/<id>.json".
+func NewFSStoredRequestFetcher(fsys fs.FS) *FSStoredRequestFetcher {
+	return &FSStoredRequestFetcher{fsys: fsys}
+}
+
+// FetchStoredRequest fetches a stored request fragment by id.
+func (f *FSStoredRequestFetcher) FetchStoredRequest(ctx context.Context, id string) ([]byte, error) {
+	return f.read("request/" + id + ".json")
+}
+
+// FetchStoredImp fetches a stored impression fragment by id.
+func (f *FSStoredRequestFetcher) FetchStoredImp(ctx context.Context, id string) ([]byte, error) {
+	return f.read("imp/" + id + ".json")
+}
+
+func (f *FSStoredRequestFetcher) read(path string) ([]byte, error) {
+	data, err := fs.ReadFile(f.fsys, path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrStoredRequestNotFound
+		}
+		return nil, err
+	}
+	return data, nil
+}
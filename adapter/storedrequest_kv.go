@@ -0,0 +1,48 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/fastly/compute-sdk-go/kvstore"
+)
+
+// KVStoredRequestFetcher resolves stored requests and stored impressions
+// from a Fastly KV store. Stored requests are kept under "request/<id>",
+// stored impressions under "imp/<id>".
+type KVStoredRequestFetcher struct {
+	store *kvstore.Store
+}
+
+// NewKVStoredRequestFetcher opens the named KV store for stored request
+// lookups.
+func NewKVStoredRequestFetcher(storeName string) (*KVStoredRequestFetcher, error) {
+	store, err := kvstore.Open(storeName)
+	if err != nil {
+		return nil, fmt.Errorf("error opening KV store %q: %v", storeName, err)
+	}
+	return &KVStoredRequestFetcher{store: store}, nil
+}
+
+// FetchStoredRequest fetches a stored request fragment by id.
+func (f *KVStoredRequestFetcher) FetchStoredRequest(ctx context.Context, id string) ([]byte, error) {
+	return f.lookup("request/" + id)
+}
+
+// FetchStoredImp fetches a stored impression fragment by id.
+func (f *KVStoredRequestFetcher) FetchStoredImp(ctx context.Context, id string) ([]byte, error) {
+	return f.lookup("imp/" + id)
+}
+
+func (f *KVStoredRequestFetcher) lookup(key string) ([]byte, error) {
+	entry, err := f.store.Lookup(key)
+	if err != nil {
+		if errors.Is(err, kvstore.ErrKeyNotFound) {
+			return nil, ErrStoredRequestNotFound
+		}
+		return nil, err
+	}
+	return io.ReadAll(entry)
+}
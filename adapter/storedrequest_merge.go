@@ -0,0 +1,72 @@
+package adapter
+
+// DeepMerge recursively merges src on top of dst. Values in src take
+// precedence; nested objects are merged key by key, and anything else
+// (scalars, arrays) is replaced wholesale.
+func DeepMerge(dst, src interface{}) interface{} {
+	dstMap, dstIsMap := dst.(map[string]interface{})
+	srcMap, srcIsMap := src.(map[string]interface{})
+	if !dstIsMap || !srcIsMap {
+		return src
+	}
+
+	merged := make(map[string]interface{}, len(dstMap))
+	for k, v := range dstMap {
+		merged[k] = v
+	}
+	for k, v := range srcMap {
+		if existing, ok := merged[k]; ok {
+			merged[k] = DeepMerge(existing, v)
+		} else {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// MergeStoredRequest merges a stored request fragment underneath an
+// incoming bid request: the incoming request's fields win on conflicts,
+// except that request.imp is merged imp-by-imp (matched on imp.id) rather
+// than replaced wholesale, so a stored request can supply default imps
+// that the caller only partially overrides.
+func MergeStoredRequest(stored, incoming map[string]interface{}) map[string]interface{} {
+	merged, _ := DeepMerge(stored, incoming).(map[string]interface{})
+
+	storedImps, storedHasImps := stored["imp"].([]interface{})
+	incomingImps, incomingHasImps := incoming["imp"].([]interface{})
+	if storedHasImps && incomingHasImps {
+		merged["imp"] = mergeImpsByID(storedImps, incomingImps)
+	}
+
+	return merged
+}
+
+// mergeImpsByID merges incoming imps on top of stored imps, matching on
+// imp.id. Imps present only on one side pass through unchanged.
+func mergeImpsByID(stored, incoming []interface{}) []interface{} {
+	indexByID := make(map[string]int, len(stored))
+	result := make([]interface{}, len(stored))
+	copy(result, stored)
+	for i, v := range stored {
+		if m, ok := v.(map[string]interface{}); ok {
+			if id, ok := m["id"].(string); ok && id != "" {
+				indexByID[id] = i
+			}
+		}
+	}
+
+	for _, v := range incoming {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			result = append(result, v)
+			continue
+		}
+		id, _ := m["id"].(string)
+		if idx, exists := indexByID[id]; id != "" && exists {
+			result[idx] = DeepMerge(result[idx], m)
+			continue
+		}
+		result = append(result, v)
+	}
+	return result
+}
@@ -0,0 +1,174 @@
+package adapter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDeepMerge(t *testing.T) {
+	tests := []struct {
+		name string
+		dst  interface{}
+		src  interface{}
+		want interface{}
+	}{
+		{
+			name: "nested keys merge instead of replacing the whole object",
+			dst: map[string]interface{}{
+				"a": map[string]interface{}{"x": 1, "y": 2},
+				"b": "dst",
+			},
+			src: map[string]interface{}{
+				"a": map[string]interface{}{"y": 20, "z": 3},
+			},
+			want: map[string]interface{}{
+				"a": map[string]interface{}{"x": 1, "y": 20, "z": 3},
+				"b": "dst",
+			},
+		},
+		{
+			name: "src scalar replaces dst wholesale",
+			dst:  map[string]interface{}{"a": 1},
+			src:  map[string]interface{}{"a": 2},
+			want: map[string]interface{}{"a": 2},
+		},
+		{
+			name: "src key absent from dst passes through",
+			dst:  map[string]interface{}{"a": 1},
+			src:  map[string]interface{}{"b": 2},
+			want: map[string]interface{}{"a": 1, "b": 2},
+		},
+		{
+			name: "a map colliding with a non-map value is replaced wholesale",
+			dst:  map[string]interface{}{"a": map[string]interface{}{"x": 1}},
+			src:  map[string]interface{}{"a": "scalar"},
+			want: map[string]interface{}{"a": "scalar"},
+		},
+		{
+			name: "a non-map colliding with a map value is replaced wholesale",
+			dst:  map[string]interface{}{"a": "scalar"},
+			src:  map[string]interface{}{"a": map[string]interface{}{"x": 1}},
+			want: map[string]interface{}{"a": map[string]interface{}{"x": 1}},
+		},
+		{
+			name: "non-map dst/src at the top level: src wins outright",
+			dst:  []interface{}{1, 2, 3},
+			src:  []interface{}{4, 5},
+			want: []interface{}{4, 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DeepMerge(tt.dst, tt.src)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DeepMerge() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeImpsByID(t *testing.T) {
+	tests := []struct {
+		name     string
+		stored   []interface{}
+		incoming []interface{}
+		want     []interface{}
+	}{
+		{
+			name:     "imp present only in stored passes through unchanged",
+			stored:   []interface{}{map[string]interface{}{"id": "1", "banner": "stored"}},
+			incoming: []interface{}{},
+			want:     []interface{}{map[string]interface{}{"id": "1", "banner": "stored"}},
+		},
+		{
+			name:     "imp present only in incoming passes through unchanged",
+			stored:   []interface{}{},
+			incoming: []interface{}{map[string]interface{}{"id": "1", "banner": "incoming"}},
+			want:     []interface{}{map[string]interface{}{"id": "1", "banner": "incoming"}},
+		},
+		{
+			name: "matching ids deep-merge, incoming fields win",
+			stored: []interface{}{
+				map[string]interface{}{"id": "1", "banner": map[string]interface{}{"w": 300, "h": 250}},
+			},
+			incoming: []interface{}{
+				map[string]interface{}{"id": "1", "banner": map[string]interface{}{"h": 600}},
+			},
+			want: []interface{}{
+				map[string]interface{}{"id": "1", "banner": map[string]interface{}{"w": 300, "h": 600}},
+			},
+		},
+		{
+			name: "two stored imps, only one overridden by id",
+			stored: []interface{}{
+				map[string]interface{}{"id": "1", "banner": "stored-1"},
+				map[string]interface{}{"id": "2", "banner": "stored-2"},
+			},
+			incoming: []interface{}{
+				map[string]interface{}{"id": "2", "banner": "incoming-2"},
+			},
+			want: []interface{}{
+				map[string]interface{}{"id": "1", "banner": "stored-1"},
+				map[string]interface{}{"id": "2", "banner": "incoming-2"},
+			},
+		},
+		{
+			name:     "imp without an id is appended rather than matched",
+			stored:   []interface{}{map[string]interface{}{"id": "1", "banner": "stored"}},
+			incoming: []interface{}{map[string]interface{}{"banner": "no-id"}},
+			want: []interface{}{
+				map[string]interface{}{"id": "1", "banner": "stored"},
+				map[string]interface{}{"banner": "no-id"},
+			},
+		},
+		{
+			name:     "incoming entry that isn't a map is appended as-is",
+			stored:   []interface{}{map[string]interface{}{"id": "1", "banner": "stored"}},
+			incoming: []interface{}{"not-an-object"},
+			want: []interface{}{
+				map[string]interface{}{"id": "1", "banner": "stored"},
+				"not-an-object",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeImpsByID(tt.stored, tt.incoming)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeImpsByID() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeStoredRequest(t *testing.T) {
+	stored := map[string]interface{}{
+		"tmax": float64(1000),
+		"imp": []interface{}{
+			map[string]interface{}{"id": "1", "banner": map[string]interface{}{"w": 300, "h": 250}},
+			map[string]interface{}{"id": "2", "banner": "stored-2"},
+		},
+	}
+	incoming := map[string]interface{}{
+		"id": "req-1",
+		"imp": []interface{}{
+			map[string]interface{}{"id": "1", "banner": map[string]interface{}{"h": 600}},
+		},
+	}
+
+	want := map[string]interface{}{
+		"tmax": float64(1000),
+		"id":   "req-1",
+		"imp": []interface{}{
+			map[string]interface{}{"id": "1", "banner": map[string]interface{}{"w": 300, "h": 600}},
+			map[string]interface{}{"id": "2", "banner": "stored-2"},
+		},
+	}
+
+	got := MergeStoredRequest(stored, incoming)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeStoredRequest() = %#v, want %#v", got, want)
+	}
+}
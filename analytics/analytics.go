@@ -0,0 +1,96 @@
+// Package analytics instruments the edge auction so operators can observe
+// it without tailing stdout: per-request and per-bidder timing, bid counts
+// and prices, and error causes, surfaced through a pluggable
+// AnalyticsModule.
+package analytics
+
+import (
+	"context"
+	"time"
+)
+
+// AuctionEvent records one full auction.
+type AuctionEvent struct {
+	RequestID  string   `json:"requestId"`
+	Bidders    []string `json:"bidders"`
+	DurationMs int64    `json:"durationMs"`
+}
+
+// BidderEvent records a single bidder's contribution to an auction.
+type BidderEvent struct {
+	RequestID  string    `json:"requestId"`
+	Bidder     string    `json:"bidder"`
+	DurationMs int64     `json:"durationMs"`
+	BidCount   int       `json:"bidCount"`
+	Prices     []float64 `json:"prices,omitempty"`
+}
+
+// ErrorEvent records a single error surfaced during an auction.
+type ErrorEvent struct {
+	RequestID string `json:"requestId"`
+	// Bidder is empty when the error isn't attributable to one bidder.
+	Bidder  string `json:"bidder,omitempty"`
+	Message string `json:"message"`
+}
+
+// AnalyticsModule receives auction telemetry. Implementations must not let
+// a slow downstream write stall the auction; keep work here minimal since
+// Fastly Compute requests run synchronously.
+type AnalyticsModule interface {
+	LogAuctionEvent(ctx context.Context, event AuctionEvent)
+	LogBidderEvent(ctx context.Context, event BidderEvent)
+	LogErrorEvent(ctx context.Context, event ErrorEvent)
+}
+
+// Flusher is implemented by AnalyticsModules that buffer events in memory
+// and need an explicit flush at the end of a request, such as
+// KVBatchModule.
+type Flusher interface {
+	Flush(now time.Time) error
+}
+
+// NoopModule discards every event. It's the default when pbs.yaml doesn't
+// configure an analytics destination.
+type NoopModule struct{}
+
+func (NoopModule) LogAuctionEvent(ctx context.Context, event AuctionEvent) {}
+func (NoopModule) LogBidderEvent(ctx context.Context, event BidderEvent)   {}
+func (NoopModule) LogErrorEvent(ctx context.Context, event ErrorEvent)     {}
+
+// MultiModule fans every event out to each member module, so pbs.yaml can
+// enable a log endpoint and a KV store at the same time.
+type MultiModule []AnalyticsModule
+
+func (m MultiModule) LogAuctionEvent(ctx context.Context, event AuctionEvent) {
+	for _, module := range m {
+		module.LogAuctionEvent(ctx, event)
+	}
+}
+
+func (m MultiModule) LogBidderEvent(ctx context.Context, event BidderEvent) {
+	for _, module := range m {
+		module.LogBidderEvent(ctx, event)
+	}
+}
+
+func (m MultiModule) LogErrorEvent(ctx context.Context, event ErrorEvent) {
+	for _, module := range m {
+		module.LogErrorEvent(ctx, event)
+	}
+}
+
+// Flush flushes every member module that implements Flusher, returning the
+// first error encountered (if any) after attempting the rest.
+func (m MultiModule) Flush(now time.Time) error {
+	var firstErr error
+	for _, module := range m {
+		flusher, ok := module.(Flusher)
+		if !ok {
+			continue
+		}
+		if err := flusher.Flush(now); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
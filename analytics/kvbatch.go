@@ -0,0 +1,85 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/fastly/compute-sdk-go/kvstore"
+)
+
+// KVBatchModule buffers one request's analytics events in memory and, on
+// Flush, writes them to their own entry under a Fastly KV store key
+// bucketed by the current UTC hour (e.g. "events/2026-07-29T14/<random
+// suffix>"), one entry per Flush call rather than one shared entry per
+// hour: this package's Store exposes no compare-and-swap or list
+// operation, so merging concurrent requests' events into a single key via
+// Lookup-then-Insert would let the last Insert silently clobber every
+// other concurrent request's batch. A downstream reader enumerates the
+// per-request keys under an hour's prefix instead of fetching one key per
+// hour.
+type KVBatchModule struct {
+	store *kvstore.Store
+
+	mu     sync.Mutex
+	events []json.RawMessage
+}
+
+// NewKVBatchModule opens storeName as the destination KV store.
+func NewKVBatchModule(storeName string) (*KVBatchModule, error) {
+	store, err := kvstore.Open(storeName)
+	if err != nil {
+		return nil, fmt.Errorf("error opening KV store %q: %v", storeName, err)
+	}
+	return &KVBatchModule{store: store}, nil
+}
+
+func (m *KVBatchModule) LogAuctionEvent(ctx context.Context, event AuctionEvent) {
+	m.buffer("auction", event)
+}
+
+func (m *KVBatchModule) LogBidderEvent(ctx context.Context, event BidderEvent) {
+	m.buffer("bidder", event)
+}
+
+func (m *KVBatchModule) LogErrorEvent(ctx context.Context, event ErrorEvent) {
+	m.buffer("error", event)
+}
+
+func (m *KVBatchModule) buffer(eventType string, event interface{}) {
+	line, err := json.Marshal(logLine{Type: eventType, Event: event})
+	if err != nil {
+		return
+	}
+
+	m.mu.Lock()
+	m.events = append(m.events, line)
+	m.mu.Unlock()
+}
+
+// Flush writes this request's buffered events to their own KV entry under
+// now's UTC hour. It should be called once, after the auction completes.
+func (m *KVBatchModule) Flush(now time.Time) error {
+	m.mu.Lock()
+	events := m.events
+	m.events = nil
+	m.mu.Unlock()
+
+	if len(events) == 0 {
+		return nil
+	}
+
+	key := fmt.Sprintf("events/%s/%016x", now.UTC().Format("2006-01-02T15"), rand.Uint64())
+
+	var buf bytes.Buffer
+	for _, line := range events {
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	return m.store.Insert(key, &buf)
+}
@@ -0,0 +1,46 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+)
+
+// logLine is the envelope written to the log endpoint: one JSON object per
+// line, tagged with the event's kind so a log pipeline can fan them out.
+type logLine struct {
+	Type  string      `json:"type"`
+	Event interface{} `json:"event"`
+}
+
+// LogEndpointModule writes each event as one newline-delimited JSON line to
+// a Fastly real-time logging endpoint (an *rtlog.Endpoint satisfies
+// io.Writer).
+type LogEndpointModule struct {
+	endpoint io.Writer
+}
+
+// NewLogEndpointModule wraps endpoint as an AnalyticsModule.
+func NewLogEndpointModule(endpoint io.Writer) *LogEndpointModule {
+	return &LogEndpointModule{endpoint: endpoint}
+}
+
+func (m *LogEndpointModule) LogAuctionEvent(ctx context.Context, event AuctionEvent) {
+	m.write("auction", event)
+}
+
+func (m *LogEndpointModule) LogBidderEvent(ctx context.Context, event BidderEvent) {
+	m.write("bidder", event)
+}
+
+func (m *LogEndpointModule) LogErrorEvent(ctx context.Context, event ErrorEvent) {
+	m.write("error", event)
+}
+
+func (m *LogEndpointModule) write(eventType string, event interface{}) {
+	line, err := json.Marshal(logLine{Type: eventType, Event: event})
+	if err != nil {
+		return
+	}
+	m.endpoint.Write(append(line, '\n'))
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"log"
+
+	"prebid-fastly-compute/analytics"
+
+	"github.com/fastly/compute-sdk-go/rtlog"
+)
+
+// buildAnalyticsModule wires up whichever analytics destinations pbs.yaml
+// configures. A log endpoint and a KV store can both be enabled at once, in
+// which case events are fanned out to both. Configuring neither yields a
+// NoopModule.
+func buildAnalyticsModule(cfg *parsedConfig, logger *log.Logger) analytics.AnalyticsModule {
+	var modules []analytics.AnalyticsModule
+
+	if name := cfg.analyticsLogEndpoint(); name != "" {
+		modules = append(modules, analytics.NewLogEndpointModule(rtlog.Open(name)))
+		logger.Printf("SUCCESS: Logging analytics events to endpoint %q", name)
+	}
+
+	if storeName := cfg.analyticsKVStore(); storeName != "" {
+		module, err := analytics.NewKVBatchModule(storeName)
+		if err != nil {
+			logger.Printf("WARNING: Failed to open analytics KV store %q: %v", storeName, err)
+		} else {
+			modules = append(modules, module)
+			logger.Printf("SUCCESS: Batching analytics events into KV store %q", storeName)
+		}
+	}
+
+	switch len(modules) {
+	case 0:
+		return analytics.NoopModule{}
+	case 1:
+		return modules[0]
+	default:
+		return analytics.MultiModule(modules)
+	}
+}
@@ -0,0 +1,564 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"time"
+
+	"prebid-fastly-compute/adapter"
+	"prebid-fastly-compute/analytics"
+	"prebid-fastly-compute/floors"
+	"prebid-fastly-compute/privacy"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+	"github.com/prebid/openrtb/v20/openrtb2"
+)
+
+// gzipCompress gzip-compresses data for bidders that require it on the wire.
+func gzipCompress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	if _, err := gw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// gzipDecompress reverses gzipCompress.
+func gzipDecompress(data []byte) ([]byte, error) {
+	gr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gr.Close()
+	return io.ReadAll(gr)
+}
+
+// impExtPrebid is the subset of imp.ext this handler needs in order to know
+// which bidders were requested for a given impression.
+type impExtPrebid struct {
+	Prebid struct {
+		Bidder map[string]json.RawMessage `json:"bidder"`
+	} `json:"prebid"`
+}
+
+// requestedBidders returns the set of bidder names referenced anywhere in
+// request.Imp[].ext.prebid.bidder, grouped by the first imp that names them;
+// the order among bidders first named in the same imp is not defined.
+func requestedBidders(request *openrtb2.BidRequest) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, imp := range request.Imp {
+		if len(imp.Ext) == 0 {
+			continue
+		}
+		var ext impExtPrebid
+		if err := json.Unmarshal(imp.Ext, &ext); err != nil {
+			continue
+		}
+		for name := range ext.Prebid.Bidder {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// bidderResult is the outcome of running one bidder's MakeRequests/Send/MakeBids
+// cycle, sent back over a channel so the auction can be bounded by TMax.
+type bidderResult struct {
+	name     string
+	response *adapter.BidderResponse
+	errs     []error
+}
+
+// targetCurrency is the currency the merged BidResponse should be
+// denominated in: request.Cur[0] if the caller named one, else USD.
+func targetCurrency(request *openrtb2.BidRequest) string {
+	if len(request.Cur) > 0 && request.Cur[0] != "" {
+		return request.Cur[0]
+	}
+	return "USD"
+}
+
+// parsePrivacySignals extracts the GDPR/TCF and USP consent signals from
+// regs.ext and user.ext, if present.
+func parsePrivacySignals(request *openrtb2.BidRequest) privacy.Signals {
+	var regsExt, userExt json.RawMessage
+	if request.Regs != nil {
+		regsExt = request.Regs.Ext
+	}
+	if request.User != nil {
+		userExt = request.User.Ext
+	}
+	return privacy.ParseSignals(regsExt, userExt)
+}
+
+// cloneRequestForBidder returns a copy of request safe to hand to a single
+// bidder's MakeRequests/MakeBids: Site, Device, and User are copied one
+// level deep (the structs themselves, not what their fields point to) so
+// a bidder that sets one of their fields in place (e.g. SmartAdServerAdapter
+// setting Site.Page) can't race with every other bidder goroutine's
+// concurrent reads of the same request. Imp is left shared by every
+// bidder; applyFloors copies the Imp slice once per auction, and only when
+// it sets floors.
+func cloneRequestForBidder(request *openrtb2.BidRequest) *openrtb2.BidRequest {
+	cloned := *request
+	if request.Site != nil {
+		site := *request.Site
+		cloned.Site = &site
+	}
+	if request.Device != nil {
+		device := *request.Device
+		cloned.Device = &device
+	}
+	if request.User != nil {
+		user := *request.User
+		cloned.User = &user
+	}
+	return &cloned
+}
+
+// applyFloors computes each impression's price floor from request.Ext's
+// ext.prebid.floors rules and returns a copy of request with
+// Imp[].BidFloor/BidFloorCur set accordingly. It returns request unchanged
+// if floors aren't configured, or if this request was exempted via
+// skipRate.
+func applyFloors(request *openrtb2.BidRequest, config *floors.Config) *openrtb2.BidRequest {
+	if config == nil || config.Skipped(rand.Float64) {
+		return request
+	}
+
+	imps := make([]openrtb2.Imp, len(request.Imp))
+	for i, imp := range request.Imp {
+		imp.BidFloor, imp.BidFloorCur = config.RuleFloor(&imp, request)
+		imps[i] = imp
+	}
+
+	floored := *request
+	floored.Imp = imps
+	return &floored
+}
+
+// enforceFloors drops any bid priced, in its impression's floor currency,
+// below that impression's floor (as set by applyFloors), returning the
+// bids that clear their floor and one error per rejected bid for
+// ext.errors. Bids for an impression with no floor (BidFloor <= 0) always
+// clear.
+func enforceFloors(request *openrtb2.BidRequest, bids []*adapter.TypedBid, converter adapter.CurrencyConverter) ([]*adapter.TypedBid, []error) {
+	type floor struct {
+		price    float64
+		currency string
+	}
+	floorsByImp := make(map[string]floor, len(request.Imp))
+	for _, imp := range request.Imp {
+		if imp.BidFloor > 0 {
+			floorsByImp[imp.ID] = floor{imp.BidFloor, imp.BidFloorCur}
+		}
+	}
+
+	kept := make([]*adapter.TypedBid, 0, len(bids))
+	var errs []error
+	for _, tb := range bids {
+		f, ok := floorsByImp[tb.Bid.ImpID]
+		if !ok {
+			kept = append(kept, tb)
+			continue
+		}
+
+		rejected, err := floors.Reject(tb.Bid.Price, targetCurrency(request), f.price, f.currency, converter)
+		if err != nil {
+			errs = append(errs, err)
+			kept = append(kept, tb)
+			continue
+		}
+		if rejected {
+			errs = append(errs, fmt.Errorf("bid %q rejected: price below floor %.2f %s", tb.Bid.ID, f.price, f.currency))
+			continue
+		}
+		kept = append(kept, tb)
+	}
+	return kept, errs
+}
+
+// errPrivacyBlocked reports that a bidder was skipped because it failed
+// GDPR/TCF consent enforcement, so mergeResponses can surface a dedicated
+// ext.errors code for it instead of the generic one.
+type errPrivacyBlocked struct {
+	reason string
+}
+
+func (e *errPrivacyBlocked) Error() string {
+	return "privacy: " + e.reason
+}
+
+// runBidder drives a single bidder end-to-end: build its HTTP request(s),
+// send them to its Fastly backend, unpack the bids from the response, and
+// normalize them (media type + currency) into the request's target
+// currency. Only the first HttpRequest is sent; PBS bidders in this tree
+// only ever return one. Its timing, bid counts/prices, and any errors are
+// reported to analyticsModule before it returns.
+func runBidder(ctx context.Context, logger *log.Logger, name string, bidder adapter.Bidder, request *openrtb2.BidRequest, cfg *parsedConfig, converter adapter.CurrencyConverter, analyticsModule analytics.AnalyticsModule, signals privacy.Signals) bidderResult {
+	start := time.Now()
+	result := runBidderUntimed(ctx, logger, name, bidder, request, cfg, converter, signals)
+
+	durationMs := time.Since(start).Milliseconds()
+	for _, err := range result.errs {
+		analyticsModule.LogErrorEvent(ctx, analytics.ErrorEvent{RequestID: request.ID, Bidder: name, Message: err.Error()})
+	}
+	prices := bidPrices(result)
+	analyticsModule.LogBidderEvent(ctx, analytics.BidderEvent{
+		RequestID:  request.ID,
+		Bidder:     name,
+		DurationMs: durationMs,
+		BidCount:   len(prices),
+		Prices:     prices,
+	})
+
+	return result
+}
+
+// bidPrices collects the price of every bid a bidder returned, for
+// analytics reporting.
+func bidPrices(result bidderResult) []float64 {
+	if result.response == nil {
+		return nil
+	}
+	prices := make([]float64, 0, len(result.response.Bids))
+	for _, bid := range result.response.Bids {
+		prices = append(prices, bid.Bid.Price)
+	}
+	return prices
+}
+
+// runBidderUntimed is the body of runBidder, split out so the timing and
+// analytics reporting in runBidder wrap the whole thing, including early
+// returns. request is first cloned (see cloneRequestForBidder) since every
+// bidder's goroutine shares the same *openrtb2.BidRequest from runAuction's
+// fan-out. It then enforces GDPR/TCF and USP consent: a bidder lacking
+// vendor or purpose 1/2 consent is blocked outright, and a bidder facing a
+// USP opt-out has its request scrubbed of PII first.
+func runBidderUntimed(ctx context.Context, logger *log.Logger, name string, bidder adapter.Bidder, request *openrtb2.BidRequest, cfg *parsedConfig, converter adapter.CurrencyConverter, signals privacy.Signals) bidderResult {
+	request = cloneRequestForBidder(request)
+
+	decision := privacy.EvaluateBidder(signals, cfg.gdprVendorID(name), cfg.uspEnforce(name))
+	switch decision.Action {
+	case privacy.Block:
+		logger.Printf("[%s] blocked: %s", name, decision.Reason)
+		return bidderResult{name: name, errs: []error{&errPrivacyBlocked{reason: decision.Reason}}}
+	case privacy.Scrub:
+		request = privacy.ScrubRequest(request)
+	}
+
+	httpRequests, errs := bidder.MakeRequests(request)
+	if len(errs) > 0 {
+		return bidderResult{name: name, errs: errs}
+	}
+	if len(httpRequests) == 0 {
+		return bidderResult{name: name}
+	}
+	req := httpRequests[0]
+
+	reqBody := req.Body
+	if req.ContentEncoding == "gzip" {
+		compressed, err := gzipCompress(reqBody)
+		if err != nil {
+			return bidderResult{name: name, errs: []error{err}}
+		}
+		reqBody = compressed
+	}
+
+	bereq, err := fsthttp.NewRequest(req.Method, req.Uri, bytes.NewReader(reqBody))
+	if err != nil {
+		return bidderResult{name: name, errs: []error{err}}
+	}
+	for k, v := range req.Headers {
+		bereq.Header.Set(k, v)
+	}
+	if req.ContentEncoding == "gzip" {
+		bereq.Header.Set("Content-Encoding", "gzip")
+	}
+
+	beresp, err := bereq.Send(ctx, backendName(name))
+	if err != nil {
+		return bidderResult{name: name, errs: []error{err}}
+	}
+	defer beresp.Body.Close()
+
+	respBody, err := io.ReadAll(beresp.Body)
+	if err != nil {
+		return bidderResult{name: name, errs: []error{err}}
+	}
+	if beresp.Header.Get("Content-Encoding") == "gzip" {
+		decompressed, err := gzipDecompress(respBody)
+		if err != nil {
+			return bidderResult{name: name, errs: []error{err}}
+		}
+		respBody = decompressed
+	}
+
+	headers := make(map[string]string, len(beresp.Header))
+	for k := range beresp.Header {
+		headers[k] = beresp.Header.Get(k)
+	}
+
+	bidResponse, errs := bidder.MakeBids(request, &adapter.HttpResponse{
+		StatusCode: beresp.StatusCode,
+		Body:       respBody,
+		Headers:    headers,
+	})
+	if len(errs) > 0 {
+		return bidderResult{name: name, errs: errs}
+	}
+	if bidResponse == nil {
+		return bidderResult{name: name}
+	}
+
+	normalized, normErrs := adapter.NormalizeBids(bidResponse.Bids, adapter.NormalizeOptions{
+		AllowedMediaTypes: cfg.allowedMediaTypes(name),
+		SeatCurrency:      bidResponse.Currency,
+		TargetCurrency:    targetCurrency(request),
+		Converter:         converter,
+	})
+
+	kept, floorErrs := enforceFloors(request, normalized, converter)
+	bidResponse.Bids = kept
+
+	logger.Printf("[%s] %d bid(s)", name, len(bidResponse.Bids))
+	return bidderResult{name: name, response: bidResponse, errs: append(normErrs, floorErrs...)}
+}
+
+// runAuction fans requests out to every bidder named in the request, in its
+// own goroutine, and collects whatever responses arrive before request.TMax
+// (or the configured default) elapses.
+func runAuction(ctx context.Context, logger *log.Logger, registry adapter.Registry, request *openrtb2.BidRequest, cfg *parsedConfig, converter adapter.CurrencyConverter, analyticsModule analytics.AnalyticsModule) map[string]bidderResult {
+	floorsConfig, err := floors.Parse(request.Ext)
+	if err != nil {
+		logger.Printf("WARNING: Failed to parse price floors from request ext: %v", err)
+		floorsConfig = nil
+	}
+	request = applyFloors(request, floorsConfig)
+
+	var bidders []string
+	for _, name := range requestedBidders(request) {
+		if _, ok := registry[name]; ok {
+			bidders = append(bidders, name)
+		} else {
+			logger.Printf("Bidder %q requested but not registered, skipping", name)
+		}
+	}
+
+	results := make(map[string]bidderResult, len(bidders))
+	if len(bidders) == 0 {
+		return results
+	}
+
+	tmax := int(request.TMax)
+	if tmax <= 0 {
+		tmax = cfg.defaultTMax()
+	}
+
+	signals := parsePrivacySignals(request)
+
+	resultCh := make(chan bidderResult, len(bidders))
+	for _, name := range bidders {
+		name, bidder := name, registry[name]
+		go func() {
+			resultCh <- runBidder(ctx, logger, name, bidder, request, cfg, converter, analyticsModule, signals)
+		}()
+	}
+
+	deadline := time.After(time.Duration(tmax) * time.Millisecond)
+	for range bidders {
+		select {
+		case result := <-resultCh:
+			results[result.name] = result
+		case <-deadline:
+			logger.Printf("TMax (%dms) hit with %d/%d bidders still outstanding", tmax, len(bidders)-len(results), len(bidders))
+			return results
+		}
+	}
+	return results
+}
+
+// bidderError mirrors prebid-server's ext.errors.<bidder>[] entries.
+type bidderError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Reason codes for bidderError.Code. errorCodePrivacyBlocked is its own
+// code so callers can distinguish "this bidder was skipped for consent
+// reasons" from any other bidder failure without parsing Message.
+const (
+	errorCodeGeneric        = 1
+	errorCodePrivacyBlocked = 2
+)
+
+// bidderErrorCode picks the ext.errors reason code for err.
+func bidderErrorCode(err error) int {
+	var privacyErr *errPrivacyBlocked
+	if errors.As(err, &privacyErr) {
+		return errorCodePrivacyBlocked
+	}
+	return errorCodeGeneric
+}
+
+// bidResponseExt is the ext object attached to the merged BidResponse.
+type bidResponseExt struct {
+	Errors map[string][]bidderError `json:"errors,omitempty"`
+}
+
+// mergeResponses combines every bidder's BidderResponse into a single
+// OpenRTB BidResponse, one SeatBid per bidder, with any errors surfaced
+// under ext.errors.<bidder>.
+func mergeResponses(request *openrtb2.BidRequest, results map[string]bidderResult) *openrtb2.BidResponse {
+	bidResponse := &openrtb2.BidResponse{
+		ID:  request.ID,
+		Cur: targetCurrency(request),
+	}
+
+	errsByBidder := make(map[string][]bidderError)
+	for name, result := range results {
+		for _, err := range result.errs {
+			errsByBidder[name] = append(errsByBidder[name], bidderError{Code: bidderErrorCode(err), Message: err.Error()})
+		}
+		if result.response == nil || len(result.response.Bids) == 0 {
+			continue
+		}
+		seatBid := openrtb2.SeatBid{Seat: name}
+		for _, typedBid := range result.response.Bids {
+			seatBid.Bid = append(seatBid.Bid, *typedBid.Bid)
+		}
+		bidResponse.SeatBid = append(bidResponse.SeatBid, seatBid)
+	}
+
+	if len(errsByBidder) > 0 {
+		ext, err := json.Marshal(bidResponseExt{Errors: errsByBidder})
+		if err == nil {
+			bidResponse.Ext = ext
+		}
+	}
+
+	return bidResponse
+}
+
+// refreshCurrencyRates fetches a fresh rate table from the backend named in
+// currency.refresh-backend, if one is configured, and loads it into
+// converter. Failures are logged and otherwise ignored: the auction
+// continues with whatever rates it already has.
+func refreshCurrencyRates(ctx context.Context, logger *log.Logger, converter *adapter.StaticCurrencyConverter, cfg *parsedConfig) {
+	backend := cfg.currencyRefreshBackend()
+	if backend == "" {
+		return
+	}
+
+	req, err := fsthttp.NewRequest("GET", "https://"+backend+"/rates.json", nil)
+	if err != nil {
+		logger.Printf("WARNING: Failed to build currency refresh request: %v", err)
+		return
+	}
+
+	resp, err := req.Send(ctx, backend)
+	if err != nil {
+		logger.Printf("WARNING: Failed to refresh currency rates from %q: %v", backend, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		logger.Printf("WARNING: Failed to read currency refresh response: %v", err)
+		return
+	}
+
+	if err := converter.LoadRates(body); err != nil {
+		logger.Printf("WARNING: Failed to load refreshed currency rates: %v", err)
+		return
+	}
+	logger.Printf("SUCCESS: Refreshed currency rates from %q", backend)
+}
+
+// handleAuction implements POST /openrtb2/auction: parse the incoming
+// OpenRTB request, fan it out to every requested bidder, and return the
+// merged BidResponse.
+func handleAuction(ctx context.Context, logger *log.Logger, registry adapter.Registry, cfg *parsedConfig, converter *adapter.StaticCurrencyConverter, storedRequests adapter.StoredRequestFetcher, analyticsModule analytics.AnalyticsModule, w fsthttp.ResponseWriter, r *fsthttp.Request) {
+	start := time.Now()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		logger.Printf("ERROR: Failed to read request body: %v", err)
+		w.WriteHeader(fsthttp.StatusBadRequest)
+		return
+	}
+
+	body, err = resolveStoredRequest(ctx, storedRequests, body)
+	if err != nil {
+		logger.Printf("ERROR: Failed to resolve stored request: %v", err)
+		w.WriteHeader(fsthttp.StatusBadRequest)
+		return
+	}
+
+	var bidRequest openrtb2.BidRequest
+	if err := json.Unmarshal(body, &bidRequest); err != nil {
+		logger.Printf("ERROR: Failed to parse bid request: %v", err)
+		w.WriteHeader(fsthttp.StatusBadRequest)
+		return
+	}
+
+	// Refresh concurrently with the bidder fan-out below instead of
+	// serially ahead of it: converter is safe for concurrent LoadRates/
+	// Convert (see adapter.StaticCurrencyConverter), so overlapping the two
+	// costs nothing, and it keeps a slow currency.refresh-backend from
+	// adding latency on top of (instead of within) TMax. If it's still in
+	// flight once bidder dispatch finishes, this auction just uses
+	// whatever rates it already had.
+	refreshDone := make(chan struct{})
+	go func() {
+		refreshCurrencyRates(ctx, logger, converter, cfg)
+		close(refreshDone)
+	}()
+
+	results := runAuction(ctx, logger, registry, &bidRequest, cfg, converter, analyticsModule)
+
+	select {
+	case <-refreshDone:
+	default:
+		logger.Printf("Currency refresh still in flight after bidder dispatch; using existing rates for this auction")
+	}
+	bidResponse := mergeResponses(&bidRequest, results)
+
+	analyticsModule.LogAuctionEvent(ctx, analytics.AuctionEvent{
+		RequestID:  bidRequest.ID,
+		Bidders:    requestedBidders(&bidRequest),
+		DurationMs: time.Since(start).Milliseconds(),
+	})
+	if flusher, ok := analyticsModule.(analytics.Flusher); ok {
+		if err := flusher.Flush(time.Now()); err != nil {
+			logger.Printf("WARNING: Failed to flush analytics events: %v", err)
+		}
+	}
+
+	respBody, err := json.Marshal(bidResponse)
+	if err != nil {
+		logger.Printf("ERROR: Failed to marshal bid response: %v", err)
+		w.WriteHeader(fsthttp.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(fsthttp.StatusOK)
+	w.Write(respBody)
+}
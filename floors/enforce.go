@@ -0,0 +1,23 @@
+package floors
+
+import (
+	"fmt"
+
+	"prebid-fastly-compute/adapter"
+)
+
+// Reject reports whether a bid priced at price (denominated in priceCur)
+// falls below a floor of floorPrice (denominated in floorCur), converting
+// price into floorCur with converter first. A non-positive floorPrice
+// never rejects, since 0 means "no floor" in the rule's key.
+func Reject(price float64, priceCur string, floorPrice float64, floorCur string, converter adapter.CurrencyConverter) (bool, error) {
+	if floorPrice <= 0 {
+		return false, nil
+	}
+
+	converted, err := converter.Convert(price, priceCur, floorCur)
+	if err != nil {
+		return false, fmt.Errorf("error converting bid price to floor currency %q: %v", floorCur, err)
+	}
+	return converted < floorPrice, nil
+}
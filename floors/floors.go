@@ -0,0 +1,133 @@
+// Package floors implements price-floor enforcement modeled on the Prebid
+// Price Floors module: it reads ext.prebid.floors from the incoming bid
+// request, selects a matching rule per impression by joining dimensions
+// (e.g. mediaType|size|domain), and enforces the result against bids
+// returned by bidders.
+package floors
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/prebid/openrtb/v20/openrtb2"
+)
+
+// defaultCurrency is used when ext.prebid.floors doesn't name one.
+const defaultCurrency = "USD"
+
+// maxSchemaFields bounds ext.prebid.floors.data.modelGroups[0].schema.fields.
+// matchRule tries every combination of fields as a wildcard, so its cost (and
+// the size of the mask table it allocates) grows as 2^len(fields). This tree
+// only ever computes three dimensions (mediaType, size, domain); anything
+// beyond this bound is rejected rather than silently truncated, since a
+// client sending dozens of field names is far more likely to be probing for
+// a denial-of-service than submitting a legitimate model.
+const maxSchemaFields = 10
+
+// modelGroup is one entry of ext.prebid.floors.data.modelGroups.
+type modelGroup struct {
+	Schema struct {
+		Fields []string `json:"fields"`
+	} `json:"schema"`
+	Values   map[string]float64 `json:"values"`
+	Default  float64            `json:"default"`
+	SkipRate int                `json:"skipRate"`
+}
+
+// floorsExt is ext.prebid.floors.
+type floorsExt struct {
+	Currency string `json:"currency"`
+	SkipRate int    `json:"skipRate"`
+	Data     struct {
+		Currency    string       `json:"currency"`
+		SkipRate    int          `json:"skipRate"`
+		ModelGroups []modelGroup `json:"modelGroups"`
+	} `json:"data"`
+}
+
+// requestExt is the subset of a bid request's top-level ext this package
+// reads.
+type requestExt struct {
+	Prebid struct {
+		Floors floorsExt `json:"floors"`
+	} `json:"prebid"`
+}
+
+// Config is a parsed set of price-floor rules, ready to be evaluated per
+// impression. Only the first model group in ext.prebid.floors.data.
+// modelGroups is used; this tree doesn't implement model-group weighting.
+type Config struct {
+	fields       []string
+	values       map[string]float64
+	defaultPrice float64
+	currency     string
+	skipRate     int
+}
+
+// Parse reads ext.prebid.floors out of a bid request's top-level ext. It
+// returns nil, nil if floors aren't configured on the request at all (not
+// an error: most requests simply won't set them).
+func Parse(requestExtBytes json.RawMessage) (*Config, error) {
+	if len(requestExtBytes) == 0 {
+		return nil, nil
+	}
+
+	var ext requestExt
+	if err := json.Unmarshal(requestExtBytes, &ext); err != nil {
+		return nil, err
+	}
+	if len(ext.Prebid.Floors.Data.ModelGroups) == 0 {
+		return nil, nil
+	}
+	group := ext.Prebid.Floors.Data.ModelGroups[0]
+	if len(group.Schema.Fields) > maxSchemaFields {
+		return nil, fmt.Errorf("ext.prebid.floors: schema.fields has %d entries, exceeds max of %d", len(group.Schema.Fields), maxSchemaFields)
+	}
+
+	currency := ext.Prebid.Floors.Data.Currency
+	if currency == "" {
+		currency = ext.Prebid.Floors.Currency
+	}
+	if currency == "" {
+		currency = defaultCurrency
+	}
+
+	skipRate := group.SkipRate
+	if skipRate == 0 {
+		skipRate = ext.Prebid.Floors.Data.SkipRate
+	}
+	if skipRate == 0 {
+		skipRate = ext.Prebid.Floors.SkipRate
+	}
+
+	return &Config{
+		fields:       group.Schema.Fields,
+		values:       group.Values,
+		defaultPrice: group.Default,
+		currency:     currency,
+		skipRate:     skipRate,
+	}, nil
+}
+
+// Skipped reports whether this request is randomly exempted from floor
+// enforcement via skipRate. The decision is made once per request, not
+// once per impression, matching the Prebid Price Floors module. rand must
+// return a value in [0, 1); callers pass rand.Float64 in production and a
+// fixed function in tests.
+func (c *Config) Skipped(rand func() float64) bool {
+	if c == nil || c.skipRate <= 0 {
+		return false
+	}
+	return rand()*100 < float64(c.skipRate)
+}
+
+// RuleFloor returns the floor price and currency that apply to imp under
+// the matching rule (or the model's default, if none matches). It returns
+// 0, "" if c is nil, i.e. floors aren't configured on the request.
+func (c *Config) RuleFloor(imp *openrtb2.Imp, request *openrtb2.BidRequest) (price float64, currency string) {
+	if c == nil {
+		return 0, ""
+	}
+	price, _ = c.matchRule(imp, request)
+	return price, c.currency
+}
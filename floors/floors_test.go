@@ -0,0 +1,57 @@
+package floors
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseRejectsOversizedSchema(t *testing.T) {
+	fields := make([]string, maxSchemaFields+1)
+	for i := range fields {
+		fields[i] = "field"
+	}
+	fieldsJSON, err := json.Marshal(fields)
+	if err != nil {
+		t.Fatalf("json.Marshal(fields): %v", err)
+	}
+
+	requestExt := json.RawMessage(`{"prebid":{"floors":{"data":{"modelGroups":[{"schema":{"fields":` +
+		string(fieldsJSON) + `},"values":{}}]}}}}`)
+
+	cfg, err := Parse(requestExt)
+	if err == nil {
+		t.Fatalf("Parse() with %d schema fields returned no error, want a rejection", len(fields))
+	}
+	if cfg != nil {
+		t.Errorf("Parse() returned a non-nil Config alongside an error")
+	}
+	if !strings.Contains(err.Error(), "schema.fields") {
+		t.Errorf("Parse() error = %q, want it to mention schema.fields", err)
+	}
+}
+
+func TestParseAllowsSchemaAtTheLimit(t *testing.T) {
+	fields := make([]string, maxSchemaFields)
+	for i := range fields {
+		fields[i] = "field"
+	}
+	fieldsJSON, err := json.Marshal(fields)
+	if err != nil {
+		t.Fatalf("json.Marshal(fields): %v", err)
+	}
+
+	requestExt := json.RawMessage(`{"prebid":{"floors":{"data":{"modelGroups":[{"schema":{"fields":` +
+		string(fieldsJSON) + `},"values":{},"default":1.23}]}}}}`)
+
+	cfg, err := Parse(requestExt)
+	if err != nil {
+		t.Fatalf("Parse() with %d schema fields returned an error: %v", len(fields), err)
+	}
+	if cfg == nil {
+		t.Fatalf("Parse() returned a nil Config")
+	}
+	if cfg.defaultPrice != 1.23 {
+		t.Errorf("Parse() defaultPrice = %v, want 1.23", cfg.defaultPrice)
+	}
+}
@@ -0,0 +1,120 @@
+package floors
+
+import (
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prebid/openrtb/v20/openrtb2"
+)
+
+// wildcard matches any value for a dimension, per the Prebid Price Floors
+// rule-key syntax.
+const wildcard = "*"
+
+// dimensionValues returns the actual value of each of c.fields for imp,
+// "*" for any field this package doesn't know how to compute, or that imp
+// doesn't set — e.g. a request with no Site has no domain.
+func (c *Config) dimensionValues(imp *openrtb2.Imp, request *openrtb2.BidRequest) []string {
+	values := make([]string, len(c.fields))
+	for i, field := range c.fields {
+		switch field {
+		case "mediaType":
+			values[i] = mediaTypeValue(imp)
+		case "size":
+			values[i] = sizeValue(imp)
+		case "domain":
+			values[i] = domainValue(request)
+		default:
+			values[i] = wildcard
+		}
+	}
+	return values
+}
+
+func mediaTypeValue(imp *openrtb2.Imp) string {
+	switch {
+	case imp.Banner != nil:
+		return "banner"
+	case imp.Video != nil:
+		return "video"
+	case imp.Native != nil:
+		return "native"
+	default:
+		return wildcard
+	}
+}
+
+func sizeValue(imp *openrtb2.Imp) string {
+	if imp.Banner != nil && len(imp.Banner.Format) > 0 {
+		f := imp.Banner.Format[0]
+		return strconv.FormatInt(f.W, 10) + "x" + strconv.FormatInt(f.H, 10)
+	}
+	if imp.Video != nil && imp.Video.W != nil && imp.Video.H != nil {
+		return strconv.FormatInt(*imp.Video.W, 10) + "x" + strconv.FormatInt(*imp.Video.H, 10)
+	}
+	return wildcard
+}
+
+func domainValue(request *openrtb2.BidRequest) string {
+	if request.Site != nil && request.Site.Domain != "" {
+		return request.Site.Domain
+	}
+	if request.App != nil && request.App.Domain != "" {
+		return request.App.Domain
+	}
+	return wildcard
+}
+
+// matchRule finds the most specific rule in c.values for imp. It tries
+// every combination of imp's actual dimension values against the
+// wildcard, from most specific (every field as-is) down to least (every
+// field wildcarded), and returns the price of the first combination that's
+// a key in c.values. Combinations are tried in the same priority order as
+// the Prebid Price Floors module: fewest wildcards first, and among
+// combinations with the same wildcard count, the rightmost fields are
+// wildcarded before the leftmost ones (e.g. for [mediaType, size, domain]:
+// "banner|300x250|example.com", then "banner|300x250|*", then
+// "banner|*|example.com", then "*|300x250|example.com", then
+// "banner|*|*", ...).
+//
+// It returns c.defaultPrice, false if no rule matches at all — the
+// catch-all ("*|*|...") is just another rule and isn't guaranteed to be
+// present.
+func (c *Config) matchRule(imp *openrtb2.Imp, request *openrtb2.BidRequest) (float64, bool) {
+	actual := c.dimensionValues(imp, request)
+	n := len(actual)
+	if n == 0 {
+		return c.defaultPrice, false
+	}
+
+	// Visit every combination in order of increasing wildcard count. The
+	// masks start out in ascending numeric order (0, 1, 2, ...), which
+	// already ties-breaks same-popcount masks with the rightmost fields
+	// (the low bits) wildcarded first; sorting by popcount only needs to
+	// reorder across different counts, so a stable sort preserves that.
+	masks := make([]int, 1<<n)
+	for mask := range masks {
+		masks[mask] = mask
+	}
+	sort.SliceStable(masks, func(i, j int) bool {
+		return bits.OnesCount(uint(masks[i])) < bits.OnesCount(uint(masks[j]))
+	})
+
+	key := make([]string, n)
+	for _, mask := range masks {
+		for i := 0; i < n; i++ {
+			if mask&(1<<(n-1-i)) != 0 {
+				key[i] = wildcard
+			} else {
+				key[i] = actual[i]
+			}
+		}
+		if price, ok := c.values[strings.Join(key, "|")]; ok {
+			return price, true
+		}
+	}
+
+	return c.defaultPrice, false
+}
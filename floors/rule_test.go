@@ -0,0 +1,139 @@
+package floors
+
+import (
+	"testing"
+
+	"github.com/prebid/openrtb/v20/openrtb2"
+)
+
+func TestMatchRule(t *testing.T) {
+	cfg := &Config{
+		fields: []string{"mediaType", "size", "domain"},
+		values: map[string]float64{
+			"banner|300x250|example.com": 1.50,
+			"banner|300x250|*":           1.00,
+			"banner|*|*":                 0.75,
+			"*|*|*":                      0.50,
+		},
+		defaultPrice: 0.10,
+	}
+
+	tests := []struct {
+		name      string
+		imp       *openrtb2.Imp
+		request   *openrtb2.BidRequest
+		wantPrice float64
+		wantFound bool
+	}{
+		{
+			name:      "exact match on every dimension",
+			imp:       bannerImp(300, 250),
+			request:   siteRequest("example.com"),
+			wantPrice: 1.50,
+			wantFound: true,
+		},
+		{
+			name:      "falls back to wildcard domain",
+			imp:       bannerImp(300, 250),
+			request:   siteRequest("other.com"),
+			wantPrice: 1.00,
+			wantFound: true,
+		},
+		{
+			name:      "falls back to wildcard size and domain",
+			imp:       bannerImp(728, 90),
+			request:   siteRequest("other.com"),
+			wantPrice: 0.75,
+			wantFound: true,
+		},
+		{
+			name:      "video imp falls through to the catch-all rule",
+			imp:       videoImp(640, 480),
+			request:   siteRequest("example.com"),
+			wantPrice: 0.50,
+			wantFound: true,
+		},
+		{
+			name:      "missing domain (no site) is treated as a wildcard",
+			imp:       bannerImp(300, 250),
+			request:   &openrtb2.BidRequest{},
+			wantPrice: 1.00,
+			wantFound: true,
+		},
+		{
+			name:      "missing size (no banner format) is treated as a wildcard",
+			imp:       &openrtb2.Imp{Banner: &openrtb2.Banner{}},
+			request:   siteRequest("example.com"),
+			wantPrice: 0.75,
+			wantFound: true,
+		},
+		{
+			name:      "every dimension missing still matches the catch-all rule",
+			imp:       &openrtb2.Imp{},
+			request:   &openrtb2.BidRequest{},
+			wantPrice: 0.50,
+			wantFound: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, found := cfg.matchRule(tt.imp, tt.request)
+			if price != tt.wantPrice || found != tt.wantFound {
+				t.Errorf("matchRule() = (%v, %v), want (%v, %v)", price, found, tt.wantPrice, tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestMatchRulePrefersFewerWildcards(t *testing.T) {
+	// A rule wildcarding only mediaType (1 wildcard) must win over a rule
+	// wildcarding size and domain (2 wildcards), even though the 2-wildcard
+	// rule's key sorts earlier in naive mask iteration order.
+	cfg := &Config{
+		fields: []string{"mediaType", "size", "domain"},
+		values: map[string]float64{
+			"*|300x250|example.com": 3.00,
+			"banner|*|*":            1.00,
+		},
+		defaultPrice: 0.10,
+	}
+
+	price, found := cfg.matchRule(bannerImp(300, 250), siteRequest("example.com"))
+	if !found {
+		t.Fatalf("matchRule() found = false, want true")
+	}
+	if price != 3.00 {
+		t.Errorf("matchRule() price = %v, want the more specific rule's 3.00", price)
+	}
+}
+
+func TestMatchRuleNoCatchAll(t *testing.T) {
+	cfg := &Config{
+		fields: []string{"mediaType"},
+		values: map[string]float64{
+			"banner": 2.00,
+		},
+		defaultPrice: 0.25,
+	}
+
+	price, found := cfg.matchRule(videoImp(640, 480), &openrtb2.BidRequest{})
+	if found {
+		t.Fatalf("matchRule() found = true for a mediaType with no rule, want false")
+	}
+	if price != 0.25 {
+		t.Errorf("matchRule() price = %v, want default 0.25", price)
+	}
+}
+
+func bannerImp(w, h int64) *openrtb2.Imp {
+	return &openrtb2.Imp{Banner: &openrtb2.Banner{Format: []openrtb2.Format{{W: w, H: h}}}}
+}
+
+func videoImp(w, h int64) *openrtb2.Imp {
+	return &openrtb2.Imp{Video: &openrtb2.Video{W: &w, H: &h}}
+}
+
+func siteRequest(domain string) *openrtb2.BidRequest {
+	return &openrtb2.BidRequest{Site: &openrtb2.Site{Domain: domain}}
+}
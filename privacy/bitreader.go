@@ -0,0 +1,37 @@
+package privacy
+
+// bitReader reads big-endian (MSB-first) bit fields out of a byte slice, as
+// used by the IAB TCF v2 core string encoding. Reading past the end of data
+// returns zero bits rather than erroring, since a truncated string should
+// decode as "no consent" instead of panicking.
+type bitReader struct {
+	data []byte
+	pos  int
+}
+
+// readUint reads the next n bits (n <= 64) and returns them as an unsigned
+// integer, most significant bit first.
+func (r *bitReader) readUint(n int) uint64 {
+	var v uint64
+	for i := 0; i < n; i++ {
+		byteIndex := r.pos / 8
+		var bit uint64
+		if byteIndex < len(r.data) {
+			bitIndex := 7 - uint(r.pos%8)
+			bit = uint64(r.data[byteIndex]>>bitIndex) & 1
+		}
+		v = v<<1 | bit
+		r.pos++
+	}
+	return v
+}
+
+// readBool reads the next single bit as a boolean.
+func (r *bitReader) readBool() bool {
+	return r.readUint(1) == 1
+}
+
+// skip advances the read position by n bits without returning them.
+func (r *bitReader) skip(n int) {
+	r.pos += n
+}
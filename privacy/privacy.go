@@ -0,0 +1,114 @@
+// Package privacy enforces GDPR/TCF and USP consent before a bidder is
+// dispatched: it decodes the IAB TCF v2 consent string and US Privacy
+// string carried on an OpenRTB request and decides, per bidder, whether the
+// bidder may be called at all and whether its request needs PII scrubbed
+// first.
+package privacy
+
+import (
+	"encoding/json"
+)
+
+// regsExt is the subset of regs.ext this package reads. Both fields moved
+// to top-level Regs attributes in later OpenRTB versions, but bidders and
+// publishers on this tree still commonly send them under ext.
+type regsExt struct {
+	GDPR      *int8  `json:"gdpr,omitempty"`
+	USPrivacy string `json:"us_privacy,omitempty"`
+}
+
+// userExt is the subset of user.ext this package reads.
+type userExt struct {
+	Consent string `json:"consent,omitempty"`
+}
+
+// Signals holds the consent information extracted from one bid request.
+type Signals struct {
+	// GDPRApplies is true when regs.ext.gdpr == 1.
+	GDPRApplies bool
+	// Consent is the decoded TCF v2 consent string from user.ext.consent,
+	// or nil if it's absent, malformed, or GDPR doesn't apply.
+	Consent *TCFConsent
+	// USPOptOut is true when regs.ext.us_privacy says the consumer opted
+	// out of the sale of their personal information.
+	USPOptOut bool
+}
+
+// ParseSignals reads regs.ext.gdpr, regs.ext.us_privacy, and
+// user.ext.consent into a Signals. Unparseable or absent ext blocks are
+// treated the same as absent signals.
+func ParseSignals(regsExtBytes, userExtBytes json.RawMessage) Signals {
+	var signals Signals
+
+	var regs regsExt
+	if len(regsExtBytes) > 0 && json.Unmarshal(regsExtBytes, &regs) == nil {
+		signals.GDPRApplies = regs.GDPR != nil && *regs.GDPR == 1
+		signals.USPOptOut = ParseUSPString(regs.USPrivacy).OptedOutOfSale
+	}
+
+	if signals.GDPRApplies && len(userExtBytes) > 0 {
+		var user userExt
+		if json.Unmarshal(userExtBytes, &user) == nil && user.Consent != "" {
+			if consent, err := DecodeTCFv2(user.Consent); err == nil {
+				signals.Consent = consent
+			}
+		}
+	}
+
+	return signals
+}
+
+// Action is what EvaluateBidder decided to do with a bidder.
+type Action int
+
+const (
+	// Allow means the bidder may be called with the request unmodified.
+	Allow Action = iota
+	// Scrub means the bidder may be called, but only after PII is
+	// stripped from the request (see ScrubRequest).
+	Scrub
+	// Block means the bidder must not be called at all.
+	Block
+)
+
+// Decision is the outcome of EvaluateBidder for one bidder.
+type Decision struct {
+	Action Action
+	// Reason is a human-readable reason code, set whenever Action isn't
+	// Allow, for reporting in the auction response's ext.errors.
+	Reason string
+}
+
+// EvaluateBidder decides what to do with a bidder given the request's
+// consent signals. vendorID is the bidder's IAB GVL vendor id from
+// pbs.yaml's adapters.<name>.gdpr.vendor-id; 0 means the adapter isn't
+// registered in the GVL and so GDPR purpose checks are skipped for it.
+// uspEnforce is adapters.<name>.usp.enforce.
+//
+// GDPR: if the request says GDPR applies and the bidder has a vendor id,
+// the bidder needs purpose 1 (storage/access) and purpose 2 (basic ads)
+// consent to be called at all; lacking it, or lacking/failing to parse a
+// consent string, blocks the bidder outright since there's no legal basis
+// to send it any data.
+//
+// USP: an opted-out consumer doesn't block the bidder, but its request
+// must be scrubbed of PII before being sent.
+func EvaluateBidder(signals Signals, vendorID int, uspEnforce bool) Decision {
+	if signals.GDPRApplies && vendorID > 0 {
+		if signals.Consent == nil {
+			return Decision{Action: Block, Reason: "gdpr: no or invalid consent string"}
+		}
+		if !signals.Consent.HasVendorConsent(vendorID) {
+			return Decision{Action: Block, Reason: "gdpr: vendor lacks consent"}
+		}
+		if !signals.Consent.HasPurposeConsent(1) || !signals.Consent.HasPurposeConsent(2) {
+			return Decision{Action: Block, Reason: "gdpr: purpose 1/2 consent not granted"}
+		}
+	}
+
+	if uspEnforce && signals.USPOptOut {
+		return Decision{Action: Scrub, Reason: "usp: consumer opted out of sale"}
+	}
+
+	return Decision{Action: Allow}
+}
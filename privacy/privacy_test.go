@@ -0,0 +1,97 @@
+package privacy
+
+import "testing"
+
+func allowAllConsent() *TCFConsent {
+	return &TCFConsent{
+		purposesConsent: purposesConsentBits(1, 2),
+		vendorConsent:   func(int) bool { return true },
+	}
+}
+
+func TestEvaluateBidder(t *testing.T) {
+	tests := []struct {
+		name       string
+		signals    Signals
+		vendorID   int
+		uspEnforce bool
+		want       Decision
+	}{
+		{
+			name:    "no GDPR, no USP: allow",
+			signals: Signals{},
+			want:    Decision{Action: Allow},
+		},
+		{
+			name:     "GDPR applies but bidder has no GVL vendor id: GDPR checks skipped",
+			signals:  Signals{GDPRApplies: true, Consent: nil},
+			vendorID: 0,
+			want:     Decision{Action: Allow},
+		},
+		{
+			name:     "GDPR applies, vendor id set, no consent string: block",
+			signals:  Signals{GDPRApplies: true, Consent: nil},
+			vendorID: 7,
+			want:     Decision{Action: Block, Reason: "gdpr: no or invalid consent string"},
+		},
+		{
+			name: "GDPR applies, consent present but vendor lacks consent: block",
+			signals: Signals{GDPRApplies: true, Consent: &TCFConsent{
+				purposesConsent: purposesConsentBits(1, 2),
+				vendorConsent:   func(int) bool { return false },
+			}},
+			vendorID: 7,
+			want:     Decision{Action: Block, Reason: "gdpr: vendor lacks consent"},
+		},
+		{
+			name: "GDPR applies, vendor consents but purpose 1/2 missing: block",
+			signals: Signals{GDPRApplies: true, Consent: &TCFConsent{
+				purposesConsent: purposesConsentBits(1), // purpose 2 missing
+				vendorConsent:   func(int) bool { return true },
+			}},
+			vendorID: 7,
+			want:     Decision{Action: Block, Reason: "gdpr: purpose 1/2 consent not granted"},
+		},
+		{
+			name:     "GDPR applies and fully consented, no USP: allow",
+			signals:  Signals{GDPRApplies: true, Consent: allowAllConsent()},
+			vendorID: 7,
+			want:     Decision{Action: Allow},
+		},
+		{
+			name:       "USP opt-out with enforcement on: scrub",
+			signals:    Signals{USPOptOut: true},
+			uspEnforce: true,
+			want:       Decision{Action: Scrub, Reason: "usp: consumer opted out of sale"},
+		},
+		{
+			name:       "USP opt-out with enforcement off: allow",
+			signals:    Signals{USPOptOut: true},
+			uspEnforce: false,
+			want:       Decision{Action: Allow},
+		},
+		{
+			name:       "GDPR fully consented and USP opt-out enforced: scrub wins over allow",
+			signals:    Signals{GDPRApplies: true, Consent: allowAllConsent(), USPOptOut: true},
+			vendorID:   7,
+			uspEnforce: true,
+			want:       Decision{Action: Scrub, Reason: "usp: consumer opted out of sale"},
+		},
+		{
+			name:       "GDPR blocks outright even when USP would also apply",
+			signals:    Signals{GDPRApplies: true, Consent: nil, USPOptOut: true},
+			vendorID:   7,
+			uspEnforce: true,
+			want:       Decision{Action: Block, Reason: "gdpr: no or invalid consent string"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EvaluateBidder(tt.signals, tt.vendorID, tt.uspEnforce)
+			if got != tt.want {
+				t.Errorf("EvaluateBidder() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
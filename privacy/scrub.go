@@ -0,0 +1,65 @@
+package privacy
+
+import (
+	"math"
+	"strings"
+
+	"github.com/prebid/openrtb/v20/openrtb2"
+)
+
+// geoPrecision is the number of decimal places device.geo.{lat,lon} are
+// rounded to when scrubbed, a grid of roughly 1.1km: enough to keep
+// coarse geo-targeting working without pinpointing a device.
+const geoPrecision = 2
+
+// ScrubRequest returns a copy of request with device.ifa and user.buyeruid
+// removed, device.ip masked (see maskIP), and device.geo.{lat,lon} rounded to
+// geoPrecision decimal places. request itself is left untouched, since the
+// same request is reused across bidders that may need different scrubbing.
+func ScrubRequest(request *openrtb2.BidRequest) *openrtb2.BidRequest {
+	scrubbed := *request
+
+	if request.Device != nil {
+		device := *request.Device
+		device.IFA = ""
+		device.IP = maskIP(device.IP)
+		if device.Geo != nil {
+			geo := *device.Geo
+			geo.Lat = roundCoord(geo.Lat)
+			geo.Lon = roundCoord(geo.Lon)
+			device.Geo = &geo
+		}
+		scrubbed.Device = &device
+	}
+
+	if request.User != nil {
+		user := *request.User
+		user.BuyerUID = ""
+		scrubbed.User = &user
+	}
+
+	return &scrubbed
+}
+
+// maskIP zeroes the last octet of an IPv4 address (e.g. "1.2.3.4" ->
+// "1.2.3.0"), leaving anything else unchanged since this tree doesn't need
+// to mask IPv6.
+func maskIP(ip string) string {
+	parts := strings.Split(ip, ".")
+	if len(parts) != 4 {
+		return ip
+	}
+	parts[3] = "0"
+	return strings.Join(parts, ".")
+}
+
+// roundCoord rounds a geo coordinate to geoPrecision decimal places,
+// leaving an absent coordinate absent.
+func roundCoord(coord *float64) *float64 {
+	if coord == nil {
+		return nil
+	}
+	scale := math.Pow(10, geoPrecision)
+	rounded := math.Round(*coord*scale) / scale
+	return &rounded
+}
@@ -0,0 +1,198 @@
+package privacy
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+// bitWriter is the inverse of bitReader: it packs big-endian (MSB-first)
+// bit fields into bytes, so tests can build known-good TCF v2 consent
+// strings without hand-computing byte values.
+type bitWriter struct {
+	bits []bool
+}
+
+func (w *bitWriter) writeBool(b bool) {
+	w.bits = append(w.bits, b)
+}
+
+func (w *bitWriter) writeUint(n int, v uint64) {
+	for i := n - 1; i >= 0; i-- {
+		w.writeBool((v>>uint(i))&1 == 1)
+	}
+}
+
+func (w *bitWriter) consentString() string {
+	data := make([]byte, (len(w.bits)+7)/8)
+	for i, b := range w.bits {
+		if b {
+			data[i/8] |= 1 << uint(7-i%8)
+		}
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// purposesConsentBits builds the 24-bit PurposesConsent field with exactly
+// the given purposes (1-24) consented.
+func purposesConsentBits(purposes ...int) uint64 {
+	set := make(map[int]bool, len(purposes))
+	for _, p := range purposes {
+		set[p] = true
+	}
+	var v uint64
+	for p := 1; p <= numPurposes; p++ {
+		v <<= 1
+		if set[p] {
+			v |= 1
+		}
+	}
+	return v
+}
+
+// writeCoreHeader writes every TCF v2 Core String field up to and
+// including PublisherCC, leaving MaxVendorId/IsRangeEncoding/the vendor
+// section for the caller to fill in per test case.
+func writeCoreHeader(w *bitWriter, purposesConsent uint64) {
+	w.writeUint(6, 2)  // Version
+	w.writeUint(36, 0) // Created
+	w.writeUint(36, 0) // LastUpdated
+	w.writeUint(12, 0) // CmpId
+	w.writeUint(12, 0) // CmpVersion
+	w.writeUint(6, 0)  // ConsentScreen
+	w.writeUint(12, 0) // ConsentLanguage
+	w.writeUint(12, 0) // VendorListVersion
+	w.writeUint(6, 0)  // TcfPolicyVersion
+	w.writeBool(false) // IsServiceSpecific
+	w.writeBool(false) // UseNonStandardStacks
+	w.writeUint(12, 0) // SpecialFeatureOptIns
+	w.writeUint(numPurposes, purposesConsent)
+	w.writeUint(numPurposes, 0) // PurposesLITransparency
+	w.writeBool(false)          // PurposeOneTreatment
+	w.writeUint(12, 0)          // PublisherCC
+}
+
+func TestDecodeTCFv2_RejectsWrongVersion(t *testing.T) {
+	w := &bitWriter{}
+	w.writeUint(6, 1) // version 1, not 2
+	if _, err := DecodeTCFv2(w.consentString()); err == nil {
+		t.Fatal("DecodeTCFv2() with version 1 returned no error, want a rejection")
+	}
+}
+
+func TestDecodeTCFv2_RejectsInvalidBase64(t *testing.T) {
+	if _, err := DecodeTCFv2("not valid base64url!!"); err == nil {
+		t.Fatal("DecodeTCFv2() with invalid base64url returned no error")
+	}
+}
+
+func TestDecodeTCFv2_Bitfield(t *testing.T) {
+	w := &bitWriter{}
+	writeCoreHeader(w, purposesConsentBits(1, 2))
+
+	const maxVendorID = 5
+	w.writeUint(16, maxVendorID)
+	w.writeBool(false) // IsRangeEncoding = false (bitfield)
+	consented := map[int]bool{1: false, 2: true, 3: false, 4: true, 5: false}
+	for id := 1; id <= maxVendorID; id++ {
+		w.writeBool(consented[id])
+	}
+
+	consent, err := DecodeTCFv2(w.consentString())
+	if err != nil {
+		t.Fatalf("DecodeTCFv2() error = %v", err)
+	}
+
+	if !consent.HasPurposeConsent(1) || !consent.HasPurposeConsent(2) {
+		t.Errorf("HasPurposeConsent(1)/(2) = false, want true")
+	}
+	if consent.HasPurposeConsent(3) {
+		t.Errorf("HasPurposeConsent(3) = true, want false")
+	}
+
+	for id, want := range consented {
+		if got := consent.HasVendorConsent(id); got != want {
+			t.Errorf("HasVendorConsent(%d) = %v, want %v", id, got, want)
+		}
+	}
+	if consent.HasVendorConsent(maxVendorID + 1) {
+		t.Errorf("HasVendorConsent(%d) = true for a vendor beyond maxVendorID, want false", maxVendorID+1)
+	}
+}
+
+func TestDecodeTCFv2_RangeEncoding(t *testing.T) {
+	tests := []struct {
+		name            string
+		defaultConsent  bool
+		insideWant      bool
+		outsideWant     bool
+		insideVendorID  int
+		outsideVendorID int
+	}{
+		{
+			name:            "default consent false, range is an opt-in exception",
+			defaultConsent:  false,
+			insideVendorID:  15,
+			outsideVendorID: 50,
+			insideWant:      true,
+			outsideWant:     false,
+		},
+		{
+			name:            "default consent true, range is an opt-out exception",
+			defaultConsent:  true,
+			insideVendorID:  15,
+			outsideVendorID: 50,
+			insideWant:      false,
+			outsideWant:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &bitWriter{}
+			writeCoreHeader(w, purposesConsentBits(1, 2))
+
+			w.writeUint(16, 100) // MaxVendorId
+			w.writeBool(true)    // IsRangeEncoding = true
+			w.writeBool(tt.defaultConsent)
+			w.writeUint(12, 1) // numEntries
+			w.writeBool(true)  // isRange
+			w.writeUint(16, 10)
+			w.writeUint(16, 20)
+
+			consent, err := DecodeTCFv2(w.consentString())
+			if err != nil {
+				t.Fatalf("DecodeTCFv2() error = %v", err)
+			}
+
+			if got := consent.HasVendorConsent(tt.insideVendorID); got != tt.insideWant {
+				t.Errorf("HasVendorConsent(%d) (inside range) = %v, want %v", tt.insideVendorID, got, tt.insideWant)
+			}
+			if got := consent.HasVendorConsent(tt.outsideVendorID); got != tt.outsideWant {
+				t.Errorf("HasVendorConsent(%d) (outside range) = %v, want %v", tt.outsideVendorID, got, tt.outsideWant)
+			}
+		})
+	}
+}
+
+func TestDecodeTCFv2_RangeEncodingSingleVendorEntry(t *testing.T) {
+	w := &bitWriter{}
+	writeCoreHeader(w, purposesConsentBits(1, 2))
+
+	w.writeUint(16, 100) // MaxVendorId
+	w.writeBool(true)    // IsRangeEncoding = true
+	w.writeBool(false)   // defaultConsent = false
+	w.writeUint(12, 1)   // numEntries
+	w.writeBool(false)   // isRange = false: single vendor id, not a range
+	w.writeUint(16, 42)
+
+	consent, err := DecodeTCFv2(w.consentString())
+	if err != nil {
+		t.Fatalf("DecodeTCFv2() error = %v", err)
+	}
+	if !consent.HasVendorConsent(42) {
+		t.Errorf("HasVendorConsent(42) = false, want true (single-entry exception)")
+	}
+	if consent.HasVendorConsent(41) {
+		t.Errorf("HasVendorConsent(41) = true, want false (not in the exception list)")
+	}
+}
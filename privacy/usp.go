@@ -0,0 +1,21 @@
+package privacy
+
+// USPString is a decoded "us_privacy" string, as defined by the IAB CCPA
+// Compliance Framework's US Privacy string specification (e.g. "1YNN").
+type USPString struct {
+	// OptedOutOfSale is true when the string's third character is 'Y':
+	// the consumer has exercised their right to opt out of the sale of
+	// their personal information.
+	OptedOutOfSale bool
+}
+
+// ParseUSPString parses an IAB US Privacy string. Malformed or unrecognized
+// strings decode as "no opt-out" rather than erroring, since an empty or
+// absent us_privacy value is the common case and just means CCPA doesn't
+// apply.
+func ParseUSPString(usp string) USPString {
+	if len(usp) != 4 {
+		return USPString{}
+	}
+	return USPString{OptedOutOfSale: usp[2] == 'Y'}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"gopkg.in/yaml.v2"
+
+	"prebid-fastly-compute/adapter"
+	"prebid-fastly-compute/adapter/forward"
+	"prebid-fastly-compute/adapter/smartadserver"
+)
+
+// builders lists every bidder this binary knows how to build, keyed by the
+// same name used in pbs.yaml's "adapters" section and in
+// imp.ext.prebid.bidder.<name>. Aso, Driftpixel, MediaGo, Adtarget, and
+// OpenWeb need nothing beyond forward.Adapter's plain request/response
+// passthrough; Adagio is the same except it requires gzip-compressed
+// request bodies.
+var builders = map[string]adapter.Builder{
+	"smartadserver": &smartadserver.Builder{},
+	"aso":           &forward.Builder{Name: "aso", DisplayName: "Aso"},
+	"driftpixel":    &forward.Builder{Name: "driftpixel", DisplayName: "Driftpixel"},
+	"mediago":       &forward.Builder{Name: "mediago", DisplayName: "MediaGo"},
+	"adtarget":      &forward.Builder{Name: "adtarget", DisplayName: "Adtarget"},
+	"openweb":       &forward.Builder{Name: "openweb", DisplayName: "OpenWeb"},
+	"adagio":        &forward.Builder{Name: "adagio", DisplayName: "Adagio", Gzip: true},
+}
+
+// defaultTMaxMs is used when pbs.yaml doesn't set auction.default-tmax-ms.
+const defaultTMaxMs = 1000
+
+// parsedConfig is every part of pbs.yaml this binary reads outside of
+// builder.BuildBidder (which unmarshals its own adapter-specific slice of
+// the raw bytes once, at startup). It's parsed once by parsePBSConfig and
+// threaded through the request path instead of being re-unmarshaled from
+// configBytes on every accessor call, since several of these (bidder media
+// types, GDPR vendor id, USP enforcement) are read once per bidder per
+// auction.
+type parsedConfig struct {
+	Adapters map[string]struct {
+		AllowedMediaTypes []string `yaml:"allowed-media-types"`
+		GDPR              struct {
+			VendorID int `yaml:"vendor-id"`
+		} `yaml:"gdpr"`
+		USP struct {
+			Enforce bool `yaml:"enforce"`
+		} `yaml:"usp"`
+	} `yaml:"adapters"`
+	Auction struct {
+		DefaultTMaxMs int `yaml:"default-tmax-ms"`
+	} `yaml:"auction"`
+	Currency struct {
+		RefreshBackend string `yaml:"refresh-backend"`
+	} `yaml:"currency"`
+	StoredRequests struct {
+		KVStore string `yaml:"kv-store"`
+	} `yaml:"stored-requests"`
+	Analytics struct {
+		LogEndpoint string `yaml:"log-endpoint"`
+		KVStore     string `yaml:"kv-store"`
+	} `yaml:"analytics"`
+}
+
+// parsePBSConfig unmarshals pbs.yaml's bytes into a parsedConfig once, for the
+// caller to hold onto and reuse for the rest of the process's lifetime.
+func parsePBSConfig(configBytes []byte) (*parsedConfig, error) {
+	var cfg parsedConfig
+	if err := yaml.Unmarshal(configBytes, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// allowedMediaTypes is adapters.<name>.allowed-media-types. An empty (or
+// absent) list means every media type is allowed.
+func (c *parsedConfig) allowedMediaTypes(name string) []string {
+	return c.Adapters[name].AllowedMediaTypes
+}
+
+// gdprVendorID is adapters.<name>.gdpr.vendor-id: the adapter's IAB Global
+// Vendor List id. 0 means the adapter isn't in the GVL, so GDPR
+// purpose/vendor consent checks are skipped for it.
+func (c *parsedConfig) gdprVendorID(name string) int {
+	return c.Adapters[name].GDPR.VendorID
+}
+
+// uspEnforce is adapters.<name>.usp.enforce: whether a consumer's CCPA
+// opt-out-of-sale should scrub PII from requests to this adapter.
+func (c *parsedConfig) uspEnforce(name string) bool {
+	return c.Adapters[name].USP.Enforce
+}
+
+// currencyRefreshBackend is currency.refresh-backend. An empty value means
+// the static rate table is never refreshed.
+func (c *parsedConfig) currencyRefreshBackend() string {
+	return c.Currency.RefreshBackend
+}
+
+// storedRequestKVStore is stored-requests.kv-store. An empty value means
+// stored requests are served from the binary's embedded fallback instead
+// of a Fastly KV store.
+func (c *parsedConfig) storedRequestKVStore() string {
+	return c.StoredRequests.KVStore
+}
+
+// analyticsLogEndpoint is analytics.log-endpoint. An empty value means
+// auction events aren't written to a Fastly real-time log endpoint.
+func (c *parsedConfig) analyticsLogEndpoint() string {
+	return c.Analytics.LogEndpoint
+}
+
+// analyticsKVStore is analytics.kv-store. An empty value means auction
+// events aren't batched into a KV store.
+func (c *parsedConfig) analyticsKVStore() string {
+	return c.Analytics.KVStore
+}
+
+// defaultTMax is auction.default-tmax-ms, falling back to defaultTMaxMs if
+// it isn't set.
+func (c *parsedConfig) defaultTMax() int {
+	if c.Auction.DefaultTMaxMs <= 0 {
+		return defaultTMaxMs
+	}
+	return c.Auction.DefaultTMaxMs
+}
+
+// buildRegistry builds one bidder per enabled adapter in pbs.yaml. Adapters
+// that are absent or disabled are skipped rather than treated as fatal,
+// since pbs.yaml only ever enables a subset of the known bidders.
+// builder.BuildBidder takes the raw config bytes, not a parsedConfig, since
+// each builder only unmarshals its own adapter-specific slice once, at
+// startup.
+func buildRegistry(configBytes []byte, logger *log.Logger) adapter.Registry {
+	registry := make(adapter.Registry)
+	for name, builder := range builders {
+		bidder, err := builder.BuildBidder(configBytes)
+		if err != nil {
+			logger.Printf("Skipping bidder %q: %v", name, err)
+			continue
+		}
+		logger.Printf("SUCCESS: Built %q bidder", name)
+		registry[name] = bidder
+	}
+	return registry
+}
+
+// backendName is the Fastly backend used to reach a given bidder, by
+// convention "<bidder>_backend" (e.g. "smartadserver_backend").
+func backendName(bidderName string) string {
+	return fmt.Sprintf("%s_backend", bidderName)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+
+	"prebid-fastly-compute/adapter"
+)
+
+//go:embed storedrequests
+var storedRequestFS embed.FS
+
+// buildStoredRequestFetcher opens the KV store named in pbs.yaml's
+// stored-requests.kv-store, falling back to the stored requests bundled
+// under storedrequests/ so lookups still resolve during local `viceroy`
+// runs where no KV store is available.
+func buildStoredRequestFetcher(cfg *parsedConfig, logger *log.Logger) adapter.StoredRequestFetcher {
+	if storeName := cfg.storedRequestKVStore(); storeName != "" {
+		fetcher, err := adapter.NewKVStoredRequestFetcher(storeName)
+		if err == nil {
+			logger.Printf("SUCCESS: Using KV store %q for stored requests", storeName)
+			return fetcher
+		}
+		logger.Printf("WARNING: Failed to open KV store %q, falling back to embedded stored requests: %v", storeName, err)
+	}
+
+	fsys, err := fs.Sub(storedRequestFS, "storedrequests")
+	if err != nil {
+		logger.Printf("ERROR: Failed to open embedded stored requests: %v", err)
+		fsys = storedRequestFS
+	}
+	return adapter.NewFSStoredRequestFetcher(fsys)
+}
+
+// resolveStoredRequest hydrates body with any stored request/stored
+// impression fragments it references, before the request is decoded into
+// an openrtb2.BidRequest and dispatched to bidders.
+func resolveStoredRequest(ctx context.Context, fetcher adapter.StoredRequestFetcher, body []byte) ([]byte, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("error parsing bid request: %v", err)
+	}
+
+	if id := storedRequestID(raw); id != "" {
+		fragment, err := fetchStoredJSON(ctx, fetcher.FetchStoredRequest, id)
+		if err != nil {
+			return nil, fmt.Errorf("error resolving stored request %q: %v", id, err)
+		}
+		raw = adapter.MergeStoredRequest(fragment, raw)
+	}
+
+	if imps, ok := raw["imp"].([]interface{}); ok {
+		for i, impVal := range imps {
+			impMap, ok := impVal.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			id := storedImpID(impMap)
+			if id == "" {
+				continue
+			}
+			fragment, err := fetchStoredJSON(ctx, fetcher.FetchStoredImp, id)
+			if err != nil {
+				return nil, fmt.Errorf("error resolving stored imp %q: %v", id, err)
+			}
+			imps[i] = adapter.DeepMerge(fragment, impMap)
+		}
+		raw["imp"] = imps
+	}
+
+	return json.Marshal(raw)
+}
+
+// fetchStoredJSON fetches a stored fragment with fetch and parses it as a
+// JSON object.
+func fetchStoredJSON(ctx context.Context, fetch func(context.Context, string) ([]byte, error), id string) (map[string]interface{}, error) {
+	data, err := fetch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	var fragment map[string]interface{}
+	if err := json.Unmarshal(data, &fragment); err != nil {
+		return nil, fmt.Errorf("error parsing stored fragment: %v", err)
+	}
+	return fragment, nil
+}
+
+// storedRequestID extracts ext.prebid.storedrequest.id from a decoded bid
+// request, returning "" if it isn't set.
+func storedRequestID(raw map[string]interface{}) string {
+	ext, _ := raw["ext"].(map[string]interface{})
+	return storedIDFromExt(ext)
+}
+
+// storedImpID extracts ext.prebid.storedrequest.id from a decoded imp,
+// returning "" if it isn't set.
+func storedImpID(imp map[string]interface{}) string {
+	ext, _ := imp["ext"].(map[string]interface{})
+	return storedIDFromExt(ext)
+}
+
+func storedIDFromExt(ext map[string]interface{}) string {
+	prebid, _ := ext["prebid"].(map[string]interface{})
+	storedrequest, _ := prebid["storedrequest"].(map[string]interface{})
+	id, _ := storedrequest["id"].(string)
+	return id
+}